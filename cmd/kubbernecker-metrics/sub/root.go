@@ -4,6 +4,7 @@ import (
 	"errors"
 	"flag"
 	"os"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/spf13/cobra"
@@ -20,6 +21,14 @@ type options struct {
 	metricsAddr      string
 	probeAddr        string
 	leaderElectionID string
+	auditWebhookAddr string
+	auditWebhookCert string
+	auditWebhookKey  string
+	auditWebhookCA   string
+	auditCountUsers  bool
+	qps              float32
+	burst            int
+	informerInterval time.Duration
 	zapOpts          zap.Options
 
 	podNamespace string
@@ -54,6 +63,14 @@ func NewCmd() *cobra.Command {
 	fs.StringVar(&opts.metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to")
 	fs.StringVar(&opts.probeAddr, "health-probe-addr", ":8081", "Listen address for health probes")
 	fs.StringVar(&opts.leaderElectionID, "leader-election-id", "kubbernecker", "ID for leader election by controller-runtime")
+	fs.Float32Var(&opts.qps, "kube-api-qps", 20, "Maximum QPS to kube-apiserver")
+	fs.IntVar(&opts.burst, "kube-api-burst", 30, "Maximum burst for throttle of requests to kube-apiserver")
+	fs.DurationVar(&opts.informerInterval, "informer-start-interval", 100*time.Millisecond, "Interval between starting informers for each resource type. If this is 0, all informers are started at once")
+	fs.StringVar(&opts.auditWebhookAddr, "audit-webhook-addr", "", "The address the audit webhook endpoint binds to. If this is empty, the endpoint is disabled")
+	fs.StringVar(&opts.auditWebhookCert, "audit-webhook-cert-file", "", "TLS certificate file for the audit webhook endpoint")
+	fs.StringVar(&opts.auditWebhookKey, "audit-webhook-key-file", "", "TLS private key file for the audit webhook endpoint")
+	fs.StringVar(&opts.auditWebhookCA, "audit-webhook-client-ca-file", "", "CA certificate file to verify the client certificates of kube-apiserver sending audit events")
+	fs.BoolVar(&opts.auditCountUsers, "audit-count-users", false, "Label the audit metrics with every user instead of only service accounts. Beware that every user makes a new series")
 
 	goflags := flag.NewFlagSet("klog", flag.ExitOnError)
 	klog.InitFlags(goflags)
@@ -76,6 +93,10 @@ func (o *options) Fill(cmd *cobra.Command, args []string) error {
 	}
 	o.podNamespace = ns
 
+	if o.auditWebhookAddr != "" && (o.auditWebhookCert == "" || o.auditWebhookKey == "" || o.auditWebhookCA == "") {
+		return errors.New("--audit-webhook-cert-file, --audit-webhook-key-file and --audit-webhook-client-ca-file are required to enable the audit webhook endpoint")
+	}
+
 	return nil
 }
 
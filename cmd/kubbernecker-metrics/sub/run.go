@@ -9,8 +9,10 @@ import (
 	"github.com/zoetrope/kubbernecker/internal/controller"
 	"github.com/zoetrope/kubbernecker/pkg/client"
 	"github.com/zoetrope/kubbernecker/pkg/config"
+	"github.com/zoetrope/kubbernecker/pkg/history"
 	"k8s.io/apimachinery/pkg/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
 	"k8s.io/utils/pointer"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
@@ -36,8 +38,15 @@ func (o *options) Run(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("invalid configurations: %w", err)
 	}
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+	restConfig := ctrl.GetConfigOrDie()
+	// Leader election should not be delayed by the throttling of the watchers
+	leaderElectionConfig := rest.CopyConfig(restConfig)
+	rateLimiter := client.NewRateLimiter(o.qps, o.burst)
+	rateLimiter.Apply(restConfig)
+
+	mgr, err := ctrl.NewManager(restConfig, ctrl.Options{
 		Scheme:                  scheme,
+		LeaderElectionConfig:    leaderElectionConfig,
 		NewClient:               client.NewCachingClient,
 		MetricsBindAddress:      o.metricsAddr,
 		HealthProbeBindAddress:  o.probeAddr,
@@ -54,20 +63,77 @@ func (o *options) Run(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to make KubeClient: %w", err)
 	}
-	wm := controller.NewWatcherManager(mgr.GetLogger(), kubeClient, cfg)
+	wm := controller.NewWatcherManager(mgr.GetLogger(), kubeClient, cfg, o.informerInterval)
+	if cfg.History != nil {
+		store, err := history.Open(mgr.GetLogger().WithName("history"), cfg.History.Path, cfg.History.Retention.Duration)
+		if err != nil {
+			return fmt.Errorf("failed to open history database: %w", err)
+		}
+		defer store.Close()
+		if err = mgr.Add(store); err != nil {
+			return fmt.Errorf("failed to add history Store: %w", err)
+		}
+		wm.SetEventSink(store)
+	}
 	if err = mgr.Add(wm); err != nil {
 		return fmt.Errorf("failed to add WatcherManager: %w", err)
 	}
 	if err = controller.SetupMetrics(wm); err != nil {
 		return fmt.Errorf("failed to setup metrics: %w", err)
 	}
+	if err = controller.SetupClientMetrics(rateLimiter); err != nil {
+		return fmt.Errorf("failed to setup client metrics: %w", err)
+	}
+	if err = mgr.AddMetricsExtraHandler(controller.APIPrefix, controller.NewAPIHandler(mgr.GetLogger().WithName("api"), wm, mgr.Elected())); err != nil {
+		return fmt.Errorf("failed to add API handler: %w", err)
+	}
+
+	if cfg.UpdateStorm != nil {
+		detector := controller.NewUpdateStormDetector(mgr.GetLogger().WithName("update-storm"), wm, mgr.GetEventRecorderFor("kubbernecker"), *cfg.UpdateStorm)
+		if err = mgr.Add(detector); err != nil {
+			return fmt.Errorf("failed to add UpdateStormDetector: %w", err)
+		}
+	}
+
+	if cfg.Notifications != nil {
+		notifier, err := controller.NewNotifier(mgr.GetLogger().WithName("notifier"), wm, *cfg.Notifications)
+		if err != nil {
+			return fmt.Errorf("failed to create Notifier: %w", err)
+		}
+		if err = mgr.Add(notifier); err != nil {
+			return fmt.Errorf("failed to add Notifier: %w", err)
+		}
+	}
+
+	if cfg.OTLP != nil {
+		exporter := controller.NewOTLPExporter(mgr.GetLogger().WithName("otlp"), wm, *cfg.OTLP)
+		if err = mgr.Add(exporter); err != nil {
+			return fmt.Errorf("failed to add OTLPExporter: %w", err)
+		}
+	}
+
+	if o.auditWebhookAddr != "" {
+		receiver := controller.NewAuditReceiver(mgr.GetLogger().WithName("audit"), mgr.GetRESTMapper(), controller.AuditReceiverOptions{
+			Addr:         o.auditWebhookAddr,
+			CertFile:     o.auditWebhookCert,
+			KeyFile:      o.auditWebhookKey,
+			ClientCAFile: o.auditWebhookCA,
+			CountUsers:   o.auditCountUsers,
+		})
+		if err = mgr.Add(receiver); err != nil {
+			return fmt.Errorf("failed to add AuditReceiver: %w", err)
+		}
+		if err = controller.SetupAuditMetrics(receiver); err != nil {
+			return fmt.Errorf("failed to setup audit metrics: %w", err)
+		}
+	}
 
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		return fmt.Errorf("unable to set up health check: %w", err)
 	}
-	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
+	if err := mgr.AddReadyzCheck("readyz", wm.ReadyChecker(mgr.Elected())); err != nil {
 		return fmt.Errorf("unable to set up ready check: %w", err)
 	}
 
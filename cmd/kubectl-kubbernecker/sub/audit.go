@@ -0,0 +1,144 @@
+package sub
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/zoetrope/kubbernecker/pkg/audit"
+	"github.com/zoetrope/kubbernecker/pkg/cobwrap"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	auditv1 "k8s.io/apiserver/pkg/apis/audit/v1"
+	"k8s.io/klog/v2"
+)
+
+type auditOptions struct {
+	files     []string
+	resources []string
+	blame     string
+	namespace string
+
+	mapper meta.RESTMapper
+}
+
+func newAuditCmd() *cobwrap.Command[*auditOptions] {
+
+	cmd := &cobwrap.Command[*auditOptions]{
+		Command: &cobra.Command{
+			Use:   "audit [FILE...]",
+			Short: "Print the number of times a resource is updated from audit logs",
+			Long: `Print the number of times a resource is updated from audit logs.
+
+The audit logs written by the log backend of kube-apiserver are read from the given files,
+or from stdin if no file or "-" is given. In addition to the watch and blame sub-commands,
+the numbers are counted for each user and user agent that made the changes.
+
+Examples:
+  # Print the number of changes of all resources
+  kubectl kubbernecker audit /var/log/kubernetes/audit.log
+
+  # Print the number of changes of ConfigMap and Deployment resources in "default" namespace
+  kubectl kubbernecker audit -n default -r configmaps -r deployments.apps audit.log
+
+  # Print managers and users that updated "test" ConfigMap resource
+  zcat audit.log.gz | kubectl kubbernecker audit --blame configmap/test
+`,
+		},
+		Options: &auditOptions{},
+	}
+
+	cmd.Command.Flags().StringArrayVarP(&cmd.Options.resources, "resource", "r", nil, "TYPE[.VERSION][.GROUP] of the resources to count. If this is not specified, all resources will be counted.")
+	cmd.Command.Flags().StringVar(&cmd.Options.blame, "blame", "", "TYPE[.VERSION][.GROUP]/NAME of the resource to print the managers that updated it.")
+
+	return cmd
+}
+
+func (o *auditOptions) Fill(cmd *cobra.Command, args []string) error {
+	root := cobwrap.GetOpt[*rootOpts](cmd)
+
+	o.files = args
+	if len(o.files) == 0 {
+		o.files = []string{"-"}
+	}
+	if root.config.Namespace != nil {
+		o.namespace = *root.config.Namespace
+	}
+	if o.blame != "" && len(o.resources) > 0 {
+		return errors.New("`--blame` and `--resource` flags cannot be used together")
+	}
+	if o.blame != "" && !strings.Contains(o.blame, "/") {
+		return errors.New("`--blame` flag must be in the form of TYPE/NAME")
+	}
+	o.mapper = audit.DefaultRESTMapper()
+
+	return nil
+}
+
+func (o *auditOptions) Run(cmd *cobra.Command, args []string) error {
+	klog.V(1).Info("run audit")
+	root := cobwrap.GetOpt[*rootOpts](cmd)
+
+	if o.blame != "" {
+		res, name, _ := strings.Cut(o.blame, "/")
+		analyzer := audit.NewBlameAnalyzer(audit.ResolveResource(o.mapper, res), o.namespace, name)
+		if err := o.decode(root.streams.In, analyzer.Add); err != nil {
+			return err
+		}
+		b, err := json.MarshalIndent(analyzer.Statistics(), "", "  ")
+		if err != nil {
+			klog.Errorf("failed to marshal json: %v", err)
+		}
+		fmt.Fprint(root.streams.Out, string(b))
+		return nil
+	}
+
+	resources := make([]schema.GroupResource, 0, len(o.resources))
+	for _, res := range o.resources {
+		resources = append(resources, audit.ResolveResource(o.mapper, res))
+	}
+	analyzer := audit.NewAnalyzer(o.mapper, o.namespace, resources)
+	if err := o.decode(root.streams.In, analyzer.Add); err != nil {
+		return err
+	}
+	for _, statistics := range analyzer.Statistics() {
+		b, err := json.MarshalIndent(statistics, "", "  ")
+		if err != nil {
+			klog.Errorf("failed to marshal json: %v", err)
+		}
+		fmt.Fprint(root.streams.Out, string(b))
+	}
+	return nil
+}
+
+func (o *auditOptions) decode(stdin io.Reader, add func(w *audit.Write)) error {
+	handler := func(ev *auditv1.Event) error {
+		if w, ok := audit.ParseWrite(ev); ok {
+			add(w)
+		}
+		return nil
+	}
+
+	for _, file := range o.files {
+		if file == "-" {
+			if err := audit.Decode(stdin, handler); err != nil {
+				return err
+			}
+			continue
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		err = audit.Decode(f, handler)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", file, err)
+		}
+	}
+	return nil
+}
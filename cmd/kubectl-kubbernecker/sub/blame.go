@@ -2,20 +2,27 @@ package sub
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"io"
+	"sync"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/zoetrope/kubbernecker/pkg/client"
 	"github.com/zoetrope/kubbernecker/pkg/cobwrap"
 	"github.com/zoetrope/kubbernecker/pkg/watch"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	toolscache "k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 type blameOptions struct {
-	kube     *client.KubeClient
+	clusterOptions
+	kubes    []*client.KubeClient
 	duration time.Duration
+	instant  bool
 }
 
 func newBlameCmd() *cobwrap.Command[*blameOptions] {
@@ -26,9 +33,18 @@ func newBlameCmd() *cobwrap.Command[*blameOptions] {
 			Short: "Print the name of managers that updated the given resource",
 			Long: `Print the name of managers that updated the given resource.
 
+If the resource is deleted and recreated while watching, the updates are counted
+for each incarnation of the resource, and the manager who recreated it is printed.
+
 Examples:
   # Print managers that updated "test" ConfigMap resource
   kubectl kubbernecker blame configmap test
+
+  # Print the fields currently owned by each manager of "test" ConfigMap resource without waiting
+  kubectl kubbernecker blame configmap test --instant
+
+  # Print managers that updated "test" ConfigMap resource in "prod-a" and "prod-b" clusters
+  kubectl kubbernecker blame configmap test --contexts prod-a,prod-b
 `,
 			Args: cobra.ExactArgs(2),
 		},
@@ -36,6 +52,8 @@ Examples:
 	}
 
 	cmd.Command.Flags().DurationVarP(&cmd.Options.duration, "duration", "d", 1*time.Minute, "")
+	cmd.Options.clusterOptions.addFlags(cmd.Command.Flags())
+	cmd.Command.Flags().BoolVar(&cmd.Options.instant, "instant", false, "If true, print the fields owned by each manager from the current managedFields instead of watching updates.")
 
 	return cmd
 }
@@ -43,11 +61,11 @@ Examples:
 func (o *blameOptions) Fill(cmd *cobra.Command, args []string) error {
 	root := cobwrap.GetOpt[*rootOpts](cmd)
 
-	kube, err := client.MakeKubeClient(root.config, true)
+	kubes, err := o.makeKubeClients(root, true)
 	if err != nil {
 		return err
 	}
-	o.kube = kube
+	o.kubes = kubes
 	return nil
 }
 
@@ -59,37 +77,151 @@ func (o *blameOptions) Run(cmd *cobra.Command, args []string) error {
 
 	root.logger.Info("run")
 
-	go func() {
-		err := o.kube.Cluster.Start(ctx)
+	if o.instant {
+		for _, kube := range o.kubes {
+			if kube.Name != "" {
+				fmt.Fprintf(root.streams.Out, "[%s]\n", kube.Name)
+			}
+			if err := o.printOwnership(ctx, root, kube, args[0], args[1]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// Start watchers of each cluster in parallel
+	var wg sync.WaitGroup
+	watchers := make([]*watch.BlameWatcher, len(o.kubes))
+	errs := make([]error, len(o.kubes))
+	for i, kube := range o.kubes {
+		kube := kube
+		go func() {
+			err := kube.Cluster.Start(ctx)
+			if err != nil {
+				root.logger.Error(err, "failed to start cluster", "cluster", kube.Name)
+			}
+		}()
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			watchers[i], errs[i] = o.startWatcher(ctx, root, kube, args[0], args[1])
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
 		if err != nil {
-			root.logger.Error(err, "failed to start cluster")
+			return err
+		}
+	}
+
+	// Start counting after the initial list, so that it is not mistaken for activity
+	synced := make([]toolscache.InformerSynced, 0, len(watchers))
+	for _, w := range watchers {
+		synced = append(synced, w.HasSynced)
+	}
+	if !toolscache.WaitForCacheSync(ctx.Done(), synced...) {
+		return nil
+	}
+	klog.V(1).Info("informers synced")
+
+	select {
+	case <-ctx.Done():
+		klog.V(3).Info("done")
+	case <-time.After(o.duration):
+		klog.V(3).Info("timed out")
+		statisticsList := make([]*watch.BlameStatistics, 0, len(watchers))
+		for _, watcher := range watchers {
+			statisticsList = append(statisticsList, watcher.Statistics())
 		}
-	}()
+		if len(statisticsList) > 1 {
+			printJSON(root.streams.Out, mergeBlameStatistics(statisticsList))
+		} else {
+			printJSON(root.streams.Out, statisticsList[0])
+		}
+		root.reportThrottling()
+	}
+	return nil
+}
 
-	gvk, err := o.kube.DetectGVK(args[0])
+func (o *blameOptions) target(kube *client.KubeClient, resource string) (*schema.GroupVersionKind, string, error) {
+	gvk, err := kube.DetectGVK(resource)
 	if err != nil {
-		return err
+		return nil, "", err
+	}
+
+	namespaced, err := kube.IsNamespaced(gvk)
+	if err != nil {
+		return nil, "", err
+	}
+	namespace := ""
+	if namespaced {
+		namespace = kube.Namespace
+	}
+	return gvk, namespace, nil
+}
+
+func (o *blameOptions) startWatcher(ctx context.Context, root *rootOpts, kube *client.KubeClient, resource, name string) (*watch.BlameWatcher, error) {
+	gvk, namespace, err := o.target(kube, resource)
+	if err != nil {
+		return nil, err
+	}
+	allowed, reason, err := kube.CanWatch(ctx, *gvk)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, fmt.Errorf("cannot watch %s: %s", gvk.String(), reason)
 	}
 
 	klog.V(2).Info("create watcher", *gvk)
-	watcher := watch.NewBlameWatcher(root.logger, o.kube, *gvk, args[1])
+	watcher := watch.NewBlameWatcher(root.logger, kube, *gvk, namespace, name)
 	klog.V(2).Info("start watcher", *gvk)
 	err = watcher.Start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return watcher, nil
+}
+
+func (o *blameOptions) printOwnership(ctx context.Context, root *rootOpts, kube *client.KubeClient, resource, name string) error {
+	gvk, namespace, err := o.target(kube, resource)
 	if err != nil {
 		return err
 	}
 
-	select {
-	case <-ctx.Done():
-		klog.V(3).Info("done")
-	case <-time.After(o.duration):
-		klog.V(3).Info("timed out")
-		statistics := watcher.Statistics()
-		b, err := json.MarshalIndent(statistics, "", "  ")
-		if err != nil {
-			klog.Errorf("failed to marshal json: %v", err)
+	meta := &metav1.PartialObjectMetadata{}
+	meta.SetGroupVersionKind(*gvk)
+	err = kube.Cluster.GetAPIReader().Get(ctx, ctrlclient.ObjectKey{Namespace: namespace, Name: name}, meta)
+	if err != nil {
+		return err
+	}
+
+	ownerships, err := watch.Ownership(meta)
+	if err != nil {
+		return err
+	}
+	for _, ownership := range ownerships {
+		header := fmt.Sprintf("%s (%s", ownership.Manager, ownership.Operation)
+		if ownership.Subresource != "" {
+			header += ", subresource: " + ownership.Subresource
 		}
-		fmt.Fprint(root.streams.Out, string(b))
+		if ownership.Time != nil {
+			header += ", " + ownership.Time.Local().Format(time.RFC3339)
+		}
+		fmt.Fprintln(root.streams.Out, header+")")
+		printFieldNodes(root.streams.Out, ownership.Fields, "")
 	}
 	return nil
 }
+
+func printFieldNodes(out io.Writer, nodes []*watch.FieldNode, indent string) {
+	for i, node := range nodes {
+		branch, next := "├── ", "│   "
+		if i == len(nodes)-1 {
+			branch, next = "└── ", "    "
+		}
+		fmt.Fprintln(out, indent+branch+node.Name)
+		printFieldNodes(out, node.Children, indent+next)
+	}
+}
@@ -0,0 +1,123 @@
+package sub
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/spf13/pflag"
+	"github.com/zoetrope/kubbernecker/pkg/client"
+	"github.com/zoetrope/kubbernecker/pkg/watch"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+type clusterOptions struct {
+	contexts    []string
+	allContexts bool
+}
+
+func (o *clusterOptions) addFlags(fs *pflag.FlagSet) {
+	fs.StringSliceVar(&o.contexts, "contexts", nil, "The names of the kubeconfig contexts to watch multiple clusters in parallel.")
+	fs.BoolVar(&o.allContexts, "all-contexts", false, "If true, watch the clusters of all contexts in the kubeconfig.")
+}
+
+// makeKubeClients creates a KubeClient for each cluster.
+// If neither `--contexts` nor `--all-contexts` flag is specified, only the current context is used.
+func (o *clusterOptions) makeKubeClients(root *rootOpts, allNamespaces bool) ([]*client.KubeClient, error) {
+	if len(o.contexts) > 0 && o.allContexts {
+		return nil, errors.New("`--contexts` and `--all-contexts` flags cannot be used together")
+	}
+
+	contexts := o.contexts
+	if o.allContexts {
+		var err error
+		contexts, err = client.Contexts(root.config)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(contexts) == 0 {
+		kube, err := client.MakeKubeClient(root.config, allNamespaces)
+		if err != nil {
+			return nil, err
+		}
+		return []*client.KubeClient{kube}, nil
+	}
+
+	kubes := make([]*client.KubeClient, 0, len(contexts))
+	for _, contextName := range contexts {
+		kube, err := client.MakeKubeClientForContext(root.config, contextName, allNamespaces)
+		if err != nil {
+			return nil, err
+		}
+		kubes = append(kubes, kube)
+	}
+	return kubes, nil
+}
+
+// clusterStatistics is the statistics of a resource type in a cluster.
+type clusterStatistics struct {
+	Namespaces  map[string]*watch.NamespaceStatistics `json:"namespaces"`
+	Relists     int                                   `json:"relists,omitempty"`
+	WatchErrors int                                   `json:"watchErrors,omitempty"`
+}
+
+// mergedStatistics is the statistics of a resource type merged from multiple clusters, keyed by the cluster name.
+type mergedStatistics struct {
+	GroupVersionKind metav1.GroupVersionKind       `json:"gvk"`
+	Clusters         map[string]*clusterStatistics `json:"clusters"`
+}
+
+// mergeStatistics merges the statistics of the same GroupVersionKind from the clusters, sorted by GroupVersionKind.
+func mergeStatistics(statisticsList []*watch.Statistics) []*mergedStatistics {
+	merged := make(map[metav1.GroupVersionKind]*mergedStatistics)
+	result := make([]*mergedStatistics, 0)
+	for _, statistics := range statisticsList {
+		m, ok := merged[statistics.GroupVersionKind]
+		if !ok {
+			m = &mergedStatistics{
+				GroupVersionKind: statistics.GroupVersionKind,
+				Clusters:         make(map[string]*clusterStatistics),
+			}
+			merged[statistics.GroupVersionKind] = m
+			result = append(result, m)
+		}
+		m.Clusters[statistics.Cluster] = &clusterStatistics{
+			Namespaces:  statistics.Namespaces,
+			Relists:     statistics.Relists,
+			WatchErrors: statistics.WatchErrors,
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].GroupVersionKind.String() < result[j].GroupVersionKind.String()
+	})
+	return result
+}
+
+// mergedBlameStatistics is the blame statistics of a resource merged from multiple clusters, keyed by the cluster name.
+type mergedBlameStatistics struct {
+	Clusters map[string]*watch.BlameStatistics `json:"clusters"`
+}
+
+// mergeBlameStatistics merges the blame statistics from the clusters. The cluster fields are moved to the keys.
+func mergeBlameStatistics(statisticsList []*watch.BlameStatistics) *mergedBlameStatistics {
+	merged := &mergedBlameStatistics{Clusters: make(map[string]*watch.BlameStatistics)}
+	for _, statistics := range statisticsList {
+		name := statistics.Cluster
+		statistics.Cluster = ""
+		merged.Clusters[name] = statistics
+	}
+	return merged
+}
+
+// printJSON prints v as an indented JSON document followed by a newline.
+func printJSON(out io.Writer, v interface{}) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		klog.Errorf("failed to marshal json: %v", err)
+	}
+	fmt.Fprintln(out, string(b))
+}
@@ -1,26 +1,37 @@
 package sub
 
 import (
+	"context"
 	"flag"
 	"os"
 	"strconv"
+	"sync"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/spf13/cobra"
 	"github.com/zoetrope/kubbernecker"
+	"github.com/zoetrope/kubbernecker/pkg/client"
 	"github.com/zoetrope/kubbernecker/pkg/cobwrap"
 	"go.uber.org/zap/zapcore"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/rest"
 	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 )
 
 type rootOpts struct {
-	loglevel int
-	config   *genericclioptions.ConfigFlags
-	streams  genericclioptions.IOStreams
-	logger   logr.Logger
+	loglevel              int
+	qps                   float32
+	burst                 int
+	informerStartInterval time.Duration
+	config                *genericclioptions.ConfigFlags
+	streams               genericclioptions.IOStreams
+	logger                logr.Logger
+
+	mu           sync.Mutex
+	rateLimiters []*client.RateLimiter
 }
 
 // NewCmd creates the root *cobra.Command of `kubectl-kubbernecker`.
@@ -41,12 +52,19 @@ func NewCmd(streams genericclioptions.IOStreams) *cobwrap.Command[*rootOpts] {
 	}
 
 	cmd.Command.PersistentFlags().IntVarP(&cmd.Options.loglevel, "log-level", "v", -1, "number for the log level verbosity")
+	cmd.Command.PersistentFlags().Float32Var(&cmd.Options.qps, "kube-api-qps", 20, "Maximum QPS to kube-apiserver for each cluster")
+	cmd.Command.PersistentFlags().IntVar(&cmd.Options.burst, "kube-api-burst", 30, "Maximum burst for throttle of requests to kube-apiserver for each cluster")
+	cmd.Command.PersistentFlags().DurationVar(&cmd.Options.informerStartInterval, "informer-start-interval", 0, "Interval between starting informers for each resource type. If this is 0, all informers are started at once")
 	config := genericclioptions.NewConfigFlags(true)
 	config.AddFlags(cmd.Command.PersistentFlags())
+	config.WrapConfigFn = cmd.Options.wrapConfig
 	cmd.Options.config = config
 
 	cobwrap.AddCommand(cmd, newWatchCmd())
 	cobwrap.AddCommand(cmd, newBlameCmd())
+	cobwrap.AddCommand(cmd, newAuditCmd())
+	cobwrap.AddCommand(cmd, newHistoryCmd())
+	cobwrap.AddCommand(cmd, newReplayCmd())
 
 	return cmd
 }
@@ -81,6 +99,46 @@ func (o *rootOpts) Run(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// wrapConfig applies client-side rate limiting to each cluster.
+func (o *rootOpts) wrapConfig(cfg *rest.Config) *rest.Config {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	limiter := client.NewRateLimiter(o.qps, o.burst)
+	o.rateLimiters = append(o.rateLimiters, limiter)
+	return limiter.Apply(cfg)
+}
+
+// reportThrottling logs the requests delayed by client-side rate limiting, if any.
+func (o *rootOpts) reportThrottling() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var requests int64
+	var duration time.Duration
+	for _, limiter := range o.rateLimiters {
+		r, d := limiter.Throttled()
+		requests += r
+		duration += d
+	}
+	if requests > 0 {
+		o.logger.Info("requests were delayed by client-side throttling, consider increasing --kube-api-qps and --kube-api-burst", "requests", requests, "duration", duration.String())
+	}
+}
+
+// waitInformerStart waits for the interval to start the next informer.
+func (o *rootOpts) waitInformerStart(ctx context.Context) error {
+	if o.informerStartInterval <= 0 {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(o.informerStartInterval):
+		return nil
+	}
+}
+
 // rootCmd represents the base command when called without any subcommands
 
 // Execute adds all child commands to the root command and sets flags appropriately.
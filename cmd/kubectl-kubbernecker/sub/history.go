@@ -0,0 +1,161 @@
+package sub
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/zoetrope/kubbernecker/pkg/audit"
+	"github.com/zoetrope/kubbernecker/pkg/cobwrap"
+	"github.com/zoetrope/kubbernecker/pkg/history"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
+)
+
+type historyOptions struct {
+	from     string
+	to       string
+	since    time.Duration
+	resource string
+	limit    int
+
+	query history.Query
+}
+
+// timeLayouts are the accepted formats of `--from` and `--to` flags. The time without a timezone is in the local time.
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+func newHistoryCmd() *cobwrap.Command[*historyOptions] {
+
+	cmd := &cobwrap.Command[*historyOptions]{
+		Command: &cobra.Command{
+			Use:   "history FILE",
+			Short: "Print the resources that churned most from the recorded events",
+			Long: `Print the resources that churned most from the recorded events.
+
+The events are read from the SQLite database recorded by kubbernecker-metrics
+or "kubectl kubbernecker watch --record-db". The resources are sorted by the number of updates.
+
+Examples:
+  # Print the resources that churned most between 02:00 and 03:00 on 2023-03-01
+  kubectl kubbernecker history out.db --from 2023-03-01T02:00 --to 2023-03-01T03:00
+
+  # Print the top 5 Deployment resources in "default" namespace updated in the last hour
+  kubectl kubbernecker history out.db --since 1h -r deployments -n default --limit 5
+`,
+			Args: cobra.ExactArgs(1),
+		},
+		Options: &historyOptions{},
+	}
+
+	cmd.Command.Flags().StringVar(&cmd.Options.from, "from", "", "The start time of the events, e.g. 2023-03-01T02:00. If this is not specified, the events from the beginning are printed.")
+	cmd.Command.Flags().StringVar(&cmd.Options.to, "to", "", "The end time of the events, e.g. 2023-03-01T03:00. If this is not specified, the events until the end are printed.")
+	cmd.Command.Flags().DurationVar(&cmd.Options.since, "since", 0, "Print the events newer than the relative duration like 1h. This cannot be used with `--from` flag.")
+	cmd.Command.Flags().StringVarP(&cmd.Options.resource, "resource", "r", "", "TYPE[.VERSION][.GROUP] of the resources to print. If this is not specified, all resources are printed.")
+	cmd.Command.Flags().IntVar(&cmd.Options.limit, "limit", 10, "The maximum number of resources to print. If this is 0, all resources are printed.")
+
+	return cmd
+}
+
+func (o *historyOptions) Fill(cmd *cobra.Command, args []string) error {
+	root := cobwrap.GetOpt[*rootOpts](cmd)
+
+	if o.since > 0 && o.from != "" {
+		return errors.New("`--since` and `--from` flags cannot be used together")
+	}
+	var err error
+	if o.from != "" {
+		if o.query.From, err = parseTime(o.from); err != nil {
+			return err
+		}
+	}
+	if o.since > 0 {
+		o.query.From = time.Now().Add(-o.since)
+	}
+	if o.to != "" {
+		if o.query.To, err = parseTime(o.to); err != nil {
+			return err
+		}
+	}
+	if o.resource != "" {
+		o.query.GroupKinds = resolveGroupKinds(o.resource)
+	}
+	if root.config.Namespace != nil {
+		o.query.Namespace = *root.config.Namespace
+	}
+	if o.limit < 0 {
+		return errors.New("`--limit` flag must not be negative")
+	}
+	o.query.Limit = o.limit
+	return nil
+}
+
+func (o *historyOptions) Run(cmd *cobra.Command, args []string) error {
+	klog.V(1).Info("run history")
+	root := cobwrap.GetOpt[*rootOpts](cmd)
+
+	store, err := history.OpenReadOnly(root.logger.WithName("history"), args[0])
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	churns, err := store.Top(cmd.Context(), o.query)
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(churns, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(root.streams.Out, string(b))
+	return nil
+}
+
+func parseTime(value string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		t, err := time.ParseInLocation(layout, value, time.Local)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, it must be in the form of 2006-01-02T15:04:05 or RFC3339", value)
+}
+
+// resolveGroupKinds converts TYPE[.VERSION][.GROUP] argument into GroupKinds without a cluster.
+// Some built-in resources are served by multiple groups (e.g. Ingress), so all of them are returned.
+// Unknown resources such as custom resources are regarded as KIND[.GROUP].
+func resolveGroupKinds(arg string) []schema.GroupKind {
+	mapper := audit.DefaultRESTMapper()
+	candidates := make([]schema.GroupVersionResource, 0, 2)
+	gvr, gr := schema.ParseResourceArg(arg)
+	if gvr != nil {
+		candidates = append(candidates, *gvr)
+	}
+	candidates = append(candidates, gr.WithVersion(""))
+	for _, candidate := range candidates {
+		kinds, err := mapper.KindsFor(candidate)
+		if err != nil || len(kinds) == 0 {
+			continue
+		}
+		seen := make(map[schema.GroupKind]bool)
+		result := make([]schema.GroupKind, 0, len(kinds))
+		for _, kind := range kinds {
+			if !seen[kind.GroupKind()] {
+				seen[kind.GroupKind()] = true
+				result = append(result, kind.GroupKind())
+			}
+		}
+		return result
+	}
+	return []schema.GroupKind{schema.ParseGroupKind(arg)}
+}
@@ -0,0 +1,220 @@
+package sub
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/zoetrope/kubbernecker/pkg/cobwrap"
+	"github.com/zoetrope/kubbernecker/pkg/watch"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+type replayOptions struct {
+	resources       []string
+	selector        string
+	includeExisting bool
+	blame           string
+	output          string
+
+	namespace   string
+	groupKinds  []schema.GroupKind
+	resSelector labels.Selector
+}
+
+func newReplayCmd() *cobwrap.Command[*replayOptions] {
+
+	cmd := &cobwrap.Command[*replayOptions]{
+		Command: &cobra.Command{
+			Use:   "replay FILE [TYPE[.VERSION][.GROUP]...]",
+			Short: "Print the number of times a resource is updated from a recorded session",
+			Long: `Print the number of times a resource is updated from a recorded session.
+
+The session recorded by "kubectl kubbernecker watch --record-session session.jsonl" is read from the given file,
+or from stdin if "-" is given. The events are counted in the same way as watch and blame sub-commands
+without accessing the cluster, so the session can be analyzed again with different filters and output formats.
+
+Examples:
+  # Print the number of updates of all resources in the session
+  kubectl kubbernecker replay session.jsonl
+
+  # Print the number of updates of Pod resources labeled "app=web" in "default" namespace as YAML
+  kubectl kubbernecker replay session.jsonl pods -n default -l app=web -o yaml
+
+  # Print managers that updated "test" ConfigMap resource in "default" namespace
+  kubectl kubbernecker replay session.jsonl -n default --blame configmap/test
+`,
+			Args: cobra.MinimumNArgs(1),
+		},
+		Options: &replayOptions{},
+	}
+
+	cmd.Command.Flags().StringVarP(&cmd.Options.selector, "selector", "l", "", "Selector (label query) to filter the resources.")
+	cmd.Command.Flags().BoolVar(&cmd.Options.includeExisting, "include-existing", false, "If true, include the resources existing at the start of the session in the output with zero counts as a baseline snapshot.")
+	cmd.Command.Flags().StringVar(&cmd.Options.blame, "blame", "", "TYPE[.VERSION][.GROUP]/NAME of the resource to print the managers that updated it.")
+	cmd.Command.Flags().StringVarP(&cmd.Options.output, "output", "o", "json", "Output format. One of: json, yaml.")
+
+	return cmd
+}
+
+func (o *replayOptions) Fill(cmd *cobra.Command, args []string) error {
+	root := cobwrap.GetOpt[*rootOpts](cmd)
+
+	o.resources = args[1:]
+	if root.config.Namespace != nil {
+		o.namespace = *root.config.Namespace
+	}
+	if o.blame != "" && len(o.resources) > 0 {
+		return errors.New("the type of resource and `--blame` flag cannot be used together")
+	}
+	if o.blame != "" && !strings.Contains(o.blame, "/") {
+		return errors.New("`--blame` flag must be in the form of TYPE/NAME")
+	}
+	if o.output != "json" && o.output != "yaml" {
+		return fmt.Errorf("unsupported output format %q", o.output)
+	}
+	for _, res := range o.resources {
+		o.groupKinds = append(o.groupKinds, resolveGroupKinds(res)...)
+	}
+
+	selector, err := labels.Parse(o.selector)
+	if err != nil {
+		return err
+	}
+	o.resSelector = selector
+	return nil
+}
+
+func (o *replayOptions) Run(cmd *cobra.Command, args []string) error {
+	klog.V(1).Info("run replay")
+	root := cobwrap.GetOpt[*rootOpts](cmd)
+
+	var in io.Reader = root.streams.In
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	if o.blame != "" {
+		return o.replayBlame(root, in)
+	}
+
+	watchers := make(map[string]*watch.Watcher)
+	err := watch.ReplaySession(in, func(event *watch.RecordedEvent, oldObj *metav1.PartialObjectMetadata) error {
+		if !o.isTarget(event) {
+			return nil
+		}
+		key := event.Cluster + "/" + event.GroupVersionKind.String()
+		watcher, ok := watchers[key]
+		if !ok {
+			gvk := schema.GroupVersionKind(event.GroupVersionKind)
+			watcher = watch.NewReplayWatcher(root.logger, event.Cluster, gvk, o.resSelector, o.includeExisting)
+			watchers[key] = watcher
+		}
+		watcher.Replay(event, oldObj)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	statisticsList := make([]*watch.Statistics, 0, len(watchers))
+	for _, watcher := range watchers {
+		statisticsList = append(statisticsList, watcher.Statistics())
+	}
+	sort.Slice(statisticsList, func(i, j int) bool {
+		if statisticsList[i].GroupVersionKind != statisticsList[j].GroupVersionKind {
+			return statisticsList[i].GroupVersionKind.String() < statisticsList[j].GroupVersionKind.String()
+		}
+		return statisticsList[i].Cluster < statisticsList[j].Cluster
+	})
+	for _, statistics := range statisticsList {
+		if err := o.print(root, statistics); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *replayOptions) replayBlame(root *rootOpts, in io.Reader) error {
+	res, name, _ := strings.Cut(o.blame, "/")
+	o.groupKinds = resolveGroupKinds(res)
+
+	watchers := make(map[string]*watch.BlameWatcher)
+	clusters := make([]string, 0)
+	err := watch.ReplaySession(in, func(event *watch.RecordedEvent, oldObj *metav1.PartialObjectMetadata) error {
+		if !o.isTarget(event) || event.Object.Name != name {
+			return nil
+		}
+		watcher, ok := watchers[event.Cluster]
+		if !ok {
+			gvk := schema.GroupVersionKind(event.GroupVersionKind)
+			watcher = watch.NewReplayBlameWatcher(root.logger, event.Cluster, gvk, event.Object.Namespace, name)
+			watchers[event.Cluster] = watcher
+			clusters = append(clusters, event.Cluster)
+		}
+		watcher.Replay(event, oldObj)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(watchers) == 0 {
+		return fmt.Errorf("%s is not found in the session", o.blame)
+	}
+
+	sort.Strings(clusters)
+	for _, cluster := range clusters {
+		if err := o.print(root, watchers[cluster].Statistics()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isTarget returns true if the event matches the resource types and the namespace.
+func (o *replayOptions) isTarget(event *watch.RecordedEvent) bool {
+	if o.namespace != "" && event.Object.Namespace != o.namespace {
+		return false
+	}
+	if len(o.groupKinds) == 0 {
+		return true
+	}
+	for _, gk := range o.groupKinds {
+		if gk.Group == event.GroupVersionKind.Group && strings.EqualFold(gk.Kind, event.GroupVersionKind.Kind) {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *replayOptions) print(root *rootOpts, v interface{}) error {
+	switch o.output {
+	case "yaml":
+		b, err := yaml.Marshal(v)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(root.streams.Out, "---\n"+string(b))
+	default:
+		b, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(root.streams.Out, string(b))
+	}
+	return nil
+}
@@ -5,27 +5,52 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/zoetrope/kubbernecker/pkg/client"
 	"github.com/zoetrope/kubbernecker/pkg/cobwrap"
+	"github.com/zoetrope/kubbernecker/pkg/history"
 	"github.com/zoetrope/kubbernecker/pkg/watch"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	toolscache "k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 )
 
 type watchOptions struct {
-	resources     []string
-	allNamespaces bool
-	allResources  bool
-	duration      time.Duration
+	clusterOptions
+	resources       []string
+	allNamespaces   bool
+	allResources    bool
+	includeExisting bool
+	duration        time.Duration
+	remote          bool
+	remoteNamespace string
+	remoteService   string
+	recordDB        string
+	recordSession   string
 
-	kube     *client.KubeClient
-	watchers []*watch.Watcher
+	kubes     []*client.KubeClient
+	store     *history.Store
+	recorder  *watch.SessionRecorder
+	mu        sync.Mutex
+	watchers  []*watch.Watcher
+	unwatched map[string]*unwatchedResources
 }
 
+// unwatchedResources holds the resources of a cluster that cannot be watched.
+type unwatchedResources struct {
+	failedGroups map[schema.GroupVersion]error
+	forbidden    map[schema.GroupVersionKind]string
+}
+
+// retryInterval is the interval to retry discovery of the failed API groups and permission checks of the forbidden resources.
+const retryInterval = 10 * time.Second
+
 func newWatchCmd() *cobwrap.Command[*watchOptions] {
 
 	cmd := &cobwrap.Command[*watchOptions]{
@@ -43,14 +68,35 @@ Examples:
 
   # Watch all resources in all namespaces
   kubectl kubbernecker watch --all-resources --all-namespaces
+
+  # Watch Deployment resources in all namespaces of "prod-a" and "prod-b" clusters
+  kubectl kubbernecker watch deployments --all-namespaces --contexts prod-a,prod-b
+
+  # Watch Pod resources in all namespaces and record the events to "out.db" to query them later with history sub-command
+  kubectl kubbernecker watch pods --all-namespaces --record-db out.db
+
+  # Watch Pod resources in all namespaces and record the raw events to "session.jsonl" to analyze them later with replay sub-command
+  kubectl kubbernecker watch pods --all-namespaces --record-session session.jsonl
+
+  # Print the statistics accumulated by kubbernecker-metrics running in the cluster without waiting
+  kubectl kubbernecker watch --all-resources --all-namespaces --remote
 `,
 		},
-		Options: &watchOptions{},
+		Options: &watchOptions{
+			unwatched: make(map[string]*unwatchedResources),
+		},
 	}
 
 	cmd.Command.Flags().BoolVarP(&cmd.Options.allResources, "all-resources", "a", false, "If true, watch all resources in the specified namespaces.")
 	cmd.Command.Flags().BoolVarP(&cmd.Options.allNamespaces, "all-namespaces", "A", false, "If true, watch the resources in all namespaces.")
+	cmd.Command.Flags().BoolVar(&cmd.Options.includeExisting, "include-existing", false, "If true, include the resources existing at the start of watching in the output with zero counts as a baseline snapshot.")
 	cmd.Command.Flags().DurationVarP(&cmd.Options.duration, "duration", "d", 1*time.Minute, "")
+	cmd.Command.Flags().StringVar(&cmd.Options.recordDB, "record-db", "", "The SQLite database file to record the events to be queried with history sub-command.")
+	cmd.Command.Flags().StringVar(&cmd.Options.recordSession, "record-session", "", "The file to record the raw events in JSON Lines format to be analyzed with replay sub-command.")
+	cmd.Command.Flags().BoolVar(&cmd.Options.remote, "remote", false, "If true, print the statistics accumulated by kubbernecker-metrics in the cluster instead of watching resources. The statistics are fetched via the service proxy of kube-apiserver.")
+	cmd.Command.Flags().StringVar(&cmd.Options.remoteNamespace, "remote-namespace", "kubbernecker", "The namespace of the kubbernecker-metrics service used with `--remote` flag.")
+	cmd.Command.Flags().StringVar(&cmd.Options.remoteService, "remote-service", "kubbernecker-metrics", "The name of the kubbernecker-metrics service used with `--remote` flag.")
+	cmd.Options.clusterOptions.addFlags(cmd.Command.Flags())
 
 	return cmd
 }
@@ -58,11 +104,11 @@ Examples:
 func (o *watchOptions) Fill(cmd *cobra.Command, args []string) error {
 	root := cobwrap.GetOpt[*rootOpts](cmd)
 
-	kube, err := client.MakeKubeClient(root.config, o.allNamespaces)
+	kubes, err := o.makeKubeClients(root, o.allNamespaces)
 	if err != nil {
 		return err
 	}
-	o.kube = kube
+	o.kubes = kubes
 	o.resources = args
 
 	if len(o.resources) > 0 && o.allResources {
@@ -71,6 +117,9 @@ func (o *watchOptions) Fill(cmd *cobra.Command, args []string) error {
 	if len(o.resources) == 0 && !o.allResources {
 		return errors.New("you must specify the type of resource to get or `--all-namespaces` flag")
 	}
+	if o.remote && (o.recordDB != "" || o.recordSession != "") {
+		return errors.New("`--remote` flag cannot be used together with `--record-db` and `--record-session` flags")
+	}
 
 	return nil
 }
@@ -82,77 +131,318 @@ func (o *watchOptions) Run(cmd *cobra.Command, args []string) error {
 	ctx, cancel := context.WithCancel(cmd.Context())
 	defer cancel()
 
-	go func() {
-		err := o.kube.Cluster.Start(ctx)
+	if o.remote {
+		return o.runRemote(ctx, root)
+	}
+
+	if o.recordSession != "" {
+		f, err := os.Create(o.recordSession)
 		if err != nil {
-			root.logger.Error(err, "failed to start cluster")
+			return err
 		}
-	}()
-
-	resources, err := o.targetResources()
-	if err != nil {
-		return err
+		o.recorder = watch.NewSessionRecorder(f)
+		defer func() {
+			if err := o.recorder.Flush(); err != nil {
+				root.logger.Error(err, "failed to record events", "path", o.recordSession)
+			}
+			f.Close()
+		}()
 	}
-
-	for _, res := range resources {
-		klog.V(2).Info("create watcher", res)
-		watcher := watch.NewWatcher(root.logger, o.kube, res, labels.Everything(), labels.Everything())
-		o.watchers = append(o.watchers, watcher)
-		klog.V(2).Info("start watcher", res)
-		err = watcher.Start(ctx)
+	if o.recordDB != "" {
+		store, err := history.Open(root.logger.WithName("history"), o.recordDB, 0)
 		if err != nil {
 			return err
 		}
+		o.store = store
+		// Stop the store after the watchers to write all events
+		storeCtx, stopStore := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			done <- store.Start(storeCtx)
+		}()
+		defer func() {
+			stopStore()
+			if err := <-done; err != nil {
+				root.logger.Error(err, "failed to record events", "path", o.recordDB)
+			}
+			store.Close()
+		}()
 	}
 
+	// Start watchers of each cluster in parallel
+	var wg sync.WaitGroup
+	errs := make([]error, len(o.kubes))
+	for i, kube := range o.kubes {
+		kube := kube
+		go func() {
+			err := kube.Cluster.Start(ctx)
+			if err != nil {
+				root.logger.Error(err, "failed to start cluster", "cluster", kube.Name)
+			}
+		}()
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = o.startWatchers(ctx, root, kube)
+		}(i)
+	}
+	wg.Wait()
+	for i := range o.kubes {
+		if errs[i] != nil {
+			return errs[i]
+		}
+	}
+
+	// Start counting after the initial list, so that it is not mistaken for activity
+	o.mu.Lock()
+	synced := make([]toolscache.InformerSynced, 0, len(o.watchers))
+	for _, w := range o.watchers {
+		synced = append(synced, w.HasSynced)
+	}
+	o.mu.Unlock()
+	if !toolscache.WaitForCacheSync(ctx.Done(), synced...) {
+		return nil
+	}
+	klog.V(1).Info("informers synced")
+
 	select {
 	case <-ctx.Done():
 		klog.V(3).Info("done")
 		return nil
 	case <-time.After(o.duration):
 		klog.V(3).Info("timed out")
+		o.mu.Lock()
+		statisticsList := make([]*watch.Statistics, 0, len(o.watchers))
 		for _, w := range o.watchers {
-			statistics := w.Statistics()
-			b, err := json.MarshalIndent(statistics, "", "  ")
-			if err != nil {
-				klog.Errorf("failed to marshal json: %v", err)
-			}
-			fmt.Fprint(root.streams.Out, string(b))
+			statisticsList = append(statisticsList, w.Statistics())
 		}
+		o.mu.Unlock()
+		o.printStatistics(root, statisticsList)
+		o.reportUnwatched(root)
+		root.reportThrottling()
 		return nil
 	}
 }
 
-func (o *watchOptions) targetResources() ([]schema.GroupVersionKind, error) {
-	targets := make([]schema.GroupVersionKind, 0)
+// runRemote prints the statistics fetched from kubbernecker-metrics of each cluster.
+func (o *watchOptions) runRemote(ctx context.Context, root *rootOpts) error {
+	statisticsList := make([]*watch.Statistics, 0)
+	for _, kube := range o.kubes {
+		list, err := o.fetchStatistics(ctx, kube)
+		if err != nil {
+			return err
+		}
+		statisticsList = append(statisticsList, list...)
+	}
+	o.printStatistics(root, statisticsList)
+	return nil
+}
 
+func (o *watchOptions) fetchStatistics(ctx context.Context, kube *client.KubeClient) ([]*watch.Statistics, error) {
+	params := make(map[string]string)
+	if !o.allNamespaces {
+		params["namespace"] = kube.Namespace
+	}
+	resources := o.resources
 	if o.allResources {
-		serverResources, err := o.kube.Discovery.ServerPreferredNamespacedResources()
+		resources = []string{""}
+	}
+
+	result := make([]*watch.Statistics, 0)
+	for _, res := range resources {
+		if res != "" {
+			params["gvk"] = res
+		}
+		body, err := kube.ProxyGet(ctx, o.remoteNamespace, o.remoteService, "metrics", "/api/v1/statistics", params)
 		if err != nil {
 			return nil, err
 		}
-		for _, resList := range serverResources {
-			for _, res := range resList.APIResources {
-				gv, err := schema.ParseGroupVersion(resList.GroupVersion)
-				if err != nil {
-					gv = schema.GroupVersion{}
-				}
-				gvk := gv.WithKind(res.Kind)
-				if client.IsExcludedResource(gvk) {
-					continue
-				}
-				targets = append(targets, gvk)
-			}
+		var list []*watch.Statistics
+		if err := json.Unmarshal(body, &list); err != nil {
+			return nil, fmt.Errorf("invalid response from %s/%s: %w", o.remoteNamespace, o.remoteService, err)
 		}
-	} else {
-		for _, res := range o.resources {
-			gvk, err := o.kube.DetectGVK(res)
-			if err != nil {
-				return nil, err
+		for _, statistics := range list {
+			statistics.Cluster = kube.Name
+		}
+		result = append(result, list...)
+	}
+	return result, nil
+}
+
+func (o *watchOptions) printStatistics(root *rootOpts, statisticsList []*watch.Statistics) {
+	if len(o.kubes) > 1 {
+		// Merge the results of the same GroupVersionKind with the cluster dimension.
+		// This applies to the results fetched with `--remote` flag as well.
+		for _, merged := range mergeStatistics(statisticsList) {
+			printJSON(root.streams.Out, merged)
+		}
+		return
+	}
+	for _, statistics := range statisticsList {
+		printJSON(root.streams.Out, statistics)
+	}
+}
+
+func (o *watchOptions) startWatchers(ctx context.Context, root *rootOpts, kube *client.KubeClient) error {
+	resources, failedGroups, err := o.targetResources(kube)
+	if err != nil {
+		return err
+	}
+	for gv, err := range failedGroups {
+		root.logger.Error(err, "failed to discover API group, the resources in the group are not watched until the discovery succeeds", "groupVersion", gv.String(), "cluster", kube.Name)
+	}
+
+	unwatched := &unwatchedResources{
+		failedGroups: failedGroups,
+		forbidden:    make(map[schema.GroupVersionKind]string),
+	}
+	o.mu.Lock()
+	o.unwatched[kube.Name] = unwatched
+	o.mu.Unlock()
+
+	watching := make(map[schema.GroupVersionKind]bool)
+	if err := o.startWatchersFor(ctx, root, kube, resources, watching); err != nil {
+		return err
+	}
+	if o.hasUnwatched(kube) {
+		go o.retryUnwatched(ctx, root, kube, watching)
+	}
+	return nil
+}
+
+// retryUnwatched periodically retries the discovery and the permission checks until all resources are watched,
+// and starts watchers for the resources of the recovered groups and the allowed resources.
+func (o *watchOptions) retryUnwatched(ctx context.Context, root *rootOpts, kube *client.KubeClient, watching map[schema.GroupVersionKind]bool) {
+	ticker := time.NewTicker(retryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		resources, failedGroups, err := o.targetResources(kube)
+		if err != nil {
+			root.logger.Error(err, "failed to retry discovery", "cluster", kube.Name)
+			continue
+		}
+		o.mu.Lock()
+		o.unwatched[kube.Name].failedGroups = failedGroups
+		o.mu.Unlock()
+		if err := o.startWatchersFor(ctx, root, kube, resources, watching); err != nil {
+			root.logger.Error(err, "failed to start watchers", "cluster", kube.Name)
+			continue
+		}
+		if !o.hasUnwatched(kube) {
+			root.logger.Info("all resources are watched", "cluster", kube.Name)
+			return
+		}
+	}
+}
+
+// startWatchersFor starts watchers for the resources that are not in watching yet.
+// Resources that the user is not allowed to watch are skipped.
+func (o *watchOptions) startWatchersFor(ctx context.Context, root *rootOpts, kube *client.KubeClient, resources []schema.GroupVersionKind, watching map[schema.GroupVersionKind]bool) error {
+	started := 0
+	for _, res := range resources {
+		if watching[res] {
+			continue
+		}
+
+		allowed, reason, err := kube.CanWatch(ctx, res)
+		if err != nil {
+			return err
+		}
+		o.mu.Lock()
+		if allowed {
+			delete(o.unwatched[kube.Name].forbidden, res)
+		} else {
+			o.unwatched[kube.Name].forbidden[res] = reason
+		}
+		o.mu.Unlock()
+		if !allowed {
+			klog.V(2).Info("skip forbidden resource", res, reason)
+			continue
+		}
+
+		if started > 0 {
+			if err := root.waitInformerStart(ctx); err != nil {
+				return err
 			}
-			targets = append(targets, *gvk)
 		}
+		klog.V(2).Info("create watcher", res)
+		watcher := watch.NewWatcher(root.logger, kube, res, labels.Everything(), labels.Everything(), o.includeExisting)
+		if o.store != nil {
+			watcher.SetEventSink(o.store)
+		}
+		if o.recorder != nil {
+			watcher.SetSessionRecorder(o.recorder)
+		}
+		klog.V(2).Info("start watcher", res)
+		err = watcher.Start(ctx)
+		if err != nil {
+			return err
+		}
+		started++
+		watching[res] = true
+
+		o.mu.Lock()
+		o.watchers = append(o.watchers, watcher)
+		o.mu.Unlock()
+	}
+	return nil
+}
+
+func (o *watchOptions) hasUnwatched(kube *client.KubeClient) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	unwatched := o.unwatched[kube.Name]
+	return len(unwatched.failedGroups) > 0 || len(unwatched.forbidden) > 0
+}
+
+// reportUnwatched prints the summary of the resources that were not watched and why.
+func (o *watchOptions) reportUnwatched(root *rootOpts) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	lines := make([]string, 0)
+	for cluster, unwatched := range o.unwatched {
+		prefix := ""
+		if cluster != "" {
+			prefix = "[" + cluster + "] "
+		}
+		for gv, err := range unwatched.failedGroups {
+			lines = append(lines, fmt.Sprintf("  %s%s: discovery failed: %v", prefix, gv.String(), err))
+		}
+		for gvk, reason := range unwatched.forbidden {
+			lines = append(lines, fmt.Sprintf("  %s%s: %s", prefix, gvk.String(), reason))
+		}
+	}
+	if len(lines) == 0 {
+		return
 	}
+	sort.Strings(lines)
+	fmt.Fprintln(root.streams.ErrOut, "\nThe following resources were not watched:")
+	for _, line := range lines {
+		fmt.Fprintln(root.streams.ErrOut, line)
+	}
+}
 
-	return targets, nil
+func (o *watchOptions) targetResources(kube *client.KubeClient) ([]schema.GroupVersionKind, map[schema.GroupVersion]error, error) {
+	if o.allResources {
+		return kube.DiscoverResources(true)
+	}
+
+	targets := make([]schema.GroupVersionKind, 0)
+	for _, res := range o.resources {
+		gvk, err := kube.DetectGVK(res)
+		if err != nil {
+			return nil, nil, err
+		}
+		targets = append(targets, *gvk)
+	}
+	return targets, nil, nil
 }
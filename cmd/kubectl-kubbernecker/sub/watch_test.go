@@ -0,0 +1,156 @@
+package sub
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/zoetrope/kubbernecker/pkg/client"
+	"github.com/zoetrope/kubbernecker/pkg/watch"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+)
+
+// fakeCluster only provides the configuration to access the fake kube-apiserver.
+type fakeCluster struct {
+	cluster.Cluster
+	config *rest.Config
+}
+
+func (c *fakeCluster) GetConfig() *rest.Config {
+	return c.config
+}
+
+var _ = Describe("Test watch sub-command with --remote flag", func() {
+	const proxyPath = "/api/v1/namespaces/kubbernecker/services/http:kubbernecker-metrics:metrics/proxy/api/v1/statistics"
+
+	var server *httptest.Server
+	var mu sync.Mutex
+	var queries []url.Values
+	var status int
+	var response string
+
+	BeforeEach(func() {
+		queries = nil
+		status = http.StatusOK
+		response = `[{"gvk": {"group": "", "version": "v1", "kind": "ConfigMap"}, "namespaces": {"default": {"resources": {"test": {"add": 1, "update": 2}}}}}]`
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			if req.URL.Path != proxyPath {
+				http.NotFound(w, req)
+				return
+			}
+			queries = append(queries, req.URL.Query())
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			io.WriteString(w, response)
+		}))
+		DeferCleanup(server.Close)
+	})
+
+	newOptions := func(names ...string) *watchOptions {
+		o := &watchOptions{
+			resources:       []string{"configmaps"},
+			remoteNamespace: "kubbernecker",
+			remoteService:   "kubbernecker-metrics",
+		}
+		for _, name := range names {
+			o.kubes = append(o.kubes, &client.KubeClient{
+				Cluster:   &fakeCluster{config: &rest.Config{Host: server.URL}},
+				Name:      name,
+				Namespace: "default",
+			})
+		}
+		return o
+	}
+	newRoot := func(out io.Writer) *rootOpts {
+		return &rootOpts{
+			streams: genericclioptions.IOStreams{Out: out},
+			logger:  logr.Discard(),
+		}
+	}
+
+	It("should get the response via the service proxy", func() {
+		kube := newOptions("").kubes[0]
+		body, err := kube.ProxyGet(context.Background(), "kubbernecker", "kubbernecker-metrics", "metrics", "/api/v1/statistics", map[string]string{"gvk": "configmaps"})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(string(body)).Should(Equal(response))
+		Expect(queries).Should(HaveLen(1))
+		Expect(queries[0].Get("gvk")).Should(Equal("configmaps"))
+
+		status = http.StatusServiceUnavailable
+		response = `{"kind": "Status", "apiVersion": "v1", "status": "Failure", "message": "no endpoints available", "code": 503}`
+		_, err = kube.ProxyGet(context.Background(), "kubbernecker", "kubbernecker-metrics", "metrics", "/api/v1/statistics", nil)
+		Expect(err).Should(MatchError(ContainSubstring("failed to get /api/v1/statistics from kubbernecker/kubbernecker-metrics")))
+	})
+
+	It("should print the statistics fetched from each cluster", func() {
+		out := &bytes.Buffer{}
+		o := newOptions("prod-b", "prod-a")
+		Expect(o.runRemote(context.Background(), newRoot(out))).Should(Succeed())
+
+		Expect(queries).Should(HaveLen(2))
+		for _, query := range queries {
+			Expect(query.Get("gvk")).Should(Equal("configmaps"))
+			Expect(query.Get("namespace")).Should(Equal("default"))
+		}
+
+		// The results of the same GroupVersionKind are merged into a single document
+		merged := &mergedStatistics{}
+		Expect(json.Unmarshal(out.Bytes(), merged)).Should(Succeed())
+		Expect(merged.GroupVersionKind.Kind).Should(Equal("ConfigMap"))
+		Expect(merged.Clusters).Should(HaveLen(2))
+		for _, name := range []string{"prod-a", "prod-b"} {
+			Expect(merged.Clusters).Should(HaveKey(name))
+			Expect(merged.Clusters[name].Namespaces["default"].Resources["test"].AddCount).Should(Equal(1))
+			Expect(merged.Clusters[name].Namespaces["default"].Resources["test"].UpdateCount).Should(Equal(2))
+		}
+	})
+
+	It("should print a document for each GroupVersionKind separated by newlines", func() {
+		response = `[{"gvk": {"group": "", "version": "v1", "kind": "ConfigMap"}, "namespaces": {}}, {"gvk": {"group": "", "version": "v1", "kind": "Secret"}, "namespaces": {}}]`
+		out := &bytes.Buffer{}
+		Expect(newOptions("").runRemote(context.Background(), newRoot(out))).Should(Succeed())
+
+		Expect(out.String()).Should(HaveSuffix("}\n"))
+		Expect(out.String()).Should(ContainSubstring("}\n{"))
+		var printed []*watch.Statistics
+		decoder := json.NewDecoder(out)
+		for decoder.More() {
+			statistics := &watch.Statistics{}
+			Expect(decoder.Decode(statistics)).Should(Succeed())
+			printed = append(printed, statistics)
+		}
+		Expect(printed).Should(HaveLen(2))
+		Expect(printed[0].GroupVersionKind.Kind).Should(Equal("ConfigMap"))
+		Expect(printed[1].GroupVersionKind.Kind).Should(Equal("Secret"))
+	})
+
+	It("should query all resources in all namespaces", func() {
+		o := newOptions("")
+		o.resources = nil
+		o.allResources = true
+		o.allNamespaces = true
+		Expect(o.runRemote(context.Background(), newRoot(io.Discard))).Should(Succeed())
+
+		Expect(queries).Should(HaveLen(1))
+		Expect(queries[0]).ShouldNot(HaveKey("gvk"))
+		Expect(queries[0]).ShouldNot(HaveKey("namespace"))
+	})
+
+	It("should fail with an invalid response", func() {
+		response = `not json`
+		err := newOptions("").runRemote(context.Background(), newRoot(io.Discard))
+		Expect(err).Should(MatchError(ContainSubstring("invalid response from kubbernecker/kubbernecker-metrics")))
+	})
+})
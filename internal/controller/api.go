@@ -0,0 +1,220 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/zoetrope/kubbernecker/pkg/watch"
+)
+
+const (
+	// APIPrefix is the path prefix of the JSON API served with the metrics.
+	APIPrefix = "/api/v1/"
+
+	defaultTopLimit = 10
+)
+
+// APIHandler serves the statistics of the watchers as JSON.
+//
+//	GET /api/v1/statistics?gvk=TYPE[.VERSION][.GROUP]&namespace=NAMESPACE
+//	GET /api/v1/top?gvk=TYPE[.VERSION][.GROUP]&namespace=NAMESPACE&limit=N
+//	GET /api/v1/blame/TYPE[.VERSION][.GROUP]/[NAMESPACE/]NAME
+type APIHandler struct {
+	logger  logr.Logger
+	wm      *WatcherManager
+	elected <-chan struct{}
+}
+
+func NewAPIHandler(logger logr.Logger, wm *WatcherManager, elected <-chan struct{}) *APIHandler {
+	return &APIHandler{
+		logger:  logger,
+		wm:      wm,
+		elected: elected,
+	}
+}
+
+func (h *APIHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	select {
+	case <-h.elected:
+	default:
+		// Only the leader watches resources
+		http.Error(w, "not the leader", http.StatusServiceUnavailable)
+		return
+	}
+
+	path := strings.TrimPrefix(req.URL.Path, APIPrefix)
+	switch {
+	case path == "statistics":
+		h.serveStatistics(w, req)
+	case path == "top":
+		h.serveTop(w, req)
+	case strings.HasPrefix(path, "blame/"):
+		h.serveBlame(w, strings.Split(strings.TrimPrefix(path, "blame/"), "/"))
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+// watchers returns the watchers for the resource type. If resource is empty, all watchers are returned.
+func (h *APIHandler) watchers(resource string) ([]*watch.Watcher, error) {
+	watchers := h.wm.Watchers()
+	if resource == "" {
+		return watchers, nil
+	}
+	gvk, err := h.wm.kube.DetectGVK(resource)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*watch.Watcher, 0, 1)
+	for _, watcher := range watchers {
+		if watcher.GroupVersionKind() == *gvk {
+			result = append(result, watcher)
+		}
+	}
+	return result, nil
+}
+
+func (h *APIHandler) serveStatistics(w http.ResponseWriter, req *http.Request) {
+	watchers, err := h.watchers(req.URL.Query().Get("gvk"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	namespace := req.URL.Query().Get("namespace")
+
+	result := make([]*watch.Statistics, 0, len(watchers))
+	for _, watcher := range watchers {
+		statistics := watcher.Statistics()
+		if namespace != "" {
+			for ns := range statistics.Namespaces {
+				if ns != namespace {
+					delete(statistics.Namespaces, ns)
+				}
+			}
+		}
+		result = append(result, statistics)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].GroupVersionKind.String() < result[j].GroupVersionKind.String()
+	})
+	h.writeJSON(w, result)
+}
+
+func (h *APIHandler) serveTop(w http.ResponseWriter, req *http.Request) {
+	watchers, err := h.watchers(req.URL.Query().Get("gvk"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	namespace := req.URL.Query().Get("namespace")
+	limit := defaultTopLimit
+	if l := req.URL.Query().Get("limit"); l != "" {
+		limit, err = strconv.Atoi(l)
+		if err != nil || limit <= 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	result := make([]*watch.TopResource, 0)
+	for _, watcher := range watchers {
+		statistics := watcher.Statistics()
+		for ns, nsStatistics := range statistics.Namespaces {
+			if namespace != "" && ns != namespace {
+				continue
+			}
+			for name, resStatistics := range nsStatistics.Resources {
+				result = append(result, &watch.TopResource{
+					GroupVersionKind:   statistics.GroupVersionKind,
+					Namespace:          ns,
+					Name:               name,
+					ResourceStatistics: *resStatistics,
+				})
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].UpdateCount != result[j].UpdateCount {
+			return result[i].UpdateCount > result[j].UpdateCount
+		}
+		if result[i].Namespace != result[j].Namespace {
+			return result[i].Namespace < result[j].Namespace
+		}
+		return result[i].Name < result[j].Name
+	})
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	h.writeJSON(w, result)
+}
+
+func (h *APIHandler) serveBlame(w http.ResponseWriter, args []string) {
+	var resource, namespace, name string
+	switch len(args) {
+	case 2:
+		resource, name = args[0], args[1]
+	case 3:
+		resource, namespace, name = args[0], args[1], args[2]
+	default:
+		http.Error(w, "the path must be /api/v1/blame/TYPE/[NAMESPACE/]NAME", http.StatusBadRequest)
+		return
+	}
+	watchers, err := h.watchers(resource)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(watchers) == 0 {
+		http.Error(w, resource+" is not watched", http.StatusNotFound)
+		return
+	}
+	watcher := watchers[0]
+
+	statistics := &watch.BlameStatistics{}
+	statistics.Managers = make(map[string]*watch.ManagerStatistics)
+	total := 0
+	for manager, count := range watcher.ManagerUpdates(namespace, name) {
+		statistics.Managers[manager] = &watch.ManagerStatistics{UpdateCount: count}
+		total += count
+	}
+	if s, ok := watcher.Resource(namespace, name); ok {
+		statistics.Unattributed = s.UpdateCount - total
+	}
+	obj, ok := watcher.Object(namespace, name)
+	if !ok && len(statistics.Managers) == 0 {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	if ok {
+		created := obj.CreationTimestamp.Time
+		statistics.UID = obj.UID
+		statistics.CreatedAt = &created
+		for _, field := range obj.ManagedFields {
+			if field.Time != nil && field.Time.After(statistics.LatestUpdate) {
+				statistics.LatestUpdate = field.Time.Time
+			}
+		}
+	}
+	if statistics.LatestUpdate.IsZero() {
+		statistics.LatestUpdate = time.Now()
+	}
+	h.writeJSON(w, statistics)
+}
+
+func (h *APIHandler) writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		h.logger.Error(err, "failed to write response")
+	}
+}
+
+var _ http.Handler = &APIHandler{}
@@ -0,0 +1,182 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+	"github.com/zoetrope/kubbernecker/pkg/audit"
+	"github.com/zoetrope/kubbernecker/pkg/client"
+	"github.com/zoetrope/kubbernecker/pkg/config"
+	"github.com/zoetrope/kubbernecker/pkg/watch"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+)
+
+// fakeCluster only provides the RESTMapper to detect the GroupVersionKind of the resources.
+type fakeCluster struct {
+	cluster.Cluster
+	mapper meta.RESTMapper
+}
+
+func (c *fakeCluster) GetRESTMapper() meta.RESTMapper {
+	return c.mapper
+}
+
+var _ = Describe("Test APIHandler", func() {
+	var elected chan struct{}
+	var handler *APIHandler
+
+	BeforeEach(func() {
+		elected = make(chan struct{})
+		wm := NewWatcherManager(ctrl.Log, nil, &config.Config{}, 0)
+		handler = NewAPIHandler(ctrl.Log, wm, elected)
+	})
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	It("should be unavailable until elected", func() {
+		Expect(serve(http.MethodGet, "/api/v1/statistics").Code).Should(Equal(http.StatusServiceUnavailable))
+
+		close(elected)
+		rec := serve(http.MethodGet, "/api/v1/statistics")
+		Expect(rec.Code).Should(Equal(http.StatusOK))
+		Expect(rec.Header().Get("Content-Type")).Should(Equal("application/json"))
+		var statistics []*watch.Statistics
+		Expect(json.Unmarshal(rec.Body.Bytes(), &statistics)).Should(Succeed())
+		Expect(statistics).Should(BeEmpty())
+	})
+
+	It("should reject invalid requests", func() {
+		close(elected)
+		Expect(serve(http.MethodPost, "/api/v1/statistics").Code).Should(Equal(http.StatusMethodNotAllowed))
+		Expect(serve(http.MethodGet, "/api/v1/unknown").Code).Should(Equal(http.StatusNotFound))
+		Expect(serve(http.MethodGet, "/api/v1/top?limit=0").Code).Should(Equal(http.StatusBadRequest))
+		Expect(serve(http.MethodGet, "/api/v1/blame/configmaps").Code).Should(Equal(http.StatusBadRequest))
+		Expect(serve(http.MethodGet, "/api/v1/blame/configmaps/default/test/extra").Code).Should(Equal(http.StatusBadRequest))
+	})
+
+	Context("with statistics", func() {
+		configMapGVK := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+		secretGVK := schema.GroupVersionKind{Version: "v1", Kind: "Secret"}
+		base := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+		newMeta := func(namespace, name, resourceVersion string, managers map[string]time.Time) *metav1.PartialObjectMetadata {
+			obj := &metav1.PartialObjectMetadata{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:       namespace,
+					Name:            name,
+					ResourceVersion: resourceVersion,
+				},
+			}
+			for manager, t := range managers {
+				obj.ManagedFields = append(obj.ManagedFields, metav1.ManagedFieldsEntry{
+					Manager: manager, Operation: metav1.ManagedFieldsOperationUpdate, Time: &metav1.Time{Time: t},
+				})
+			}
+			return obj
+		}
+
+		BeforeEach(func() {
+			kube := &client.KubeClient{Cluster: &fakeCluster{mapper: audit.DefaultRESTMapper()}}
+			wm := NewWatcherManager(ctrl.Log, kube, &config.Config{}, 0)
+
+			configMaps := watch.NewWatcherWithSource(ctrl.Log, nil, "", configMapGVK, labels.Everything(), false)
+			v1 := newMeta("default", "test", "1", map[string]time.Time{"helm": base})
+			v2 := newMeta("default", "test", "2", map[string]time.Time{"helm": base, "my-operator": base.Add(time.Second)})
+			v3 := newMeta("default", "test", "3", map[string]time.Time{"helm": base.Add(2 * time.Second), "my-operator": base.Add(time.Second)})
+			// The timestamps are not changed, so the update cannot be attributed to a manager
+			v4 := newMeta("default", "test", "4", map[string]time.Time{"helm": base.Add(2 * time.Second), "my-operator": base.Add(time.Second)})
+			configMaps.OnEvent(&watch.SourceEvent{Type: "add", Object: v1})
+			configMaps.OnEvent(&watch.SourceEvent{Type: "update", Object: v2, OldObject: v1})
+			configMaps.OnEvent(&watch.SourceEvent{Type: "update", Object: v3, OldObject: v2})
+			configMaps.OnEvent(&watch.SourceEvent{Type: "update", Object: v4, OldObject: v3})
+			other := newMeta("kube-system", "other", "5", map[string]time.Time{"kubectl": base})
+			configMaps.OnEvent(&watch.SourceEvent{Type: "add", Object: other})
+			configMaps.OnEvent(&watch.SourceEvent{Type: "delete", Object: other})
+
+			secrets := watch.NewWatcherWithSource(ctrl.Log, nil, "", secretGVK, labels.Everything(), false)
+			s1 := newMeta("default", "token", "6", map[string]time.Time{"kubectl": base})
+			s2 := newMeta("default", "token", "7", map[string]time.Time{"kubectl": base.Add(time.Second)})
+			secrets.OnEvent(&watch.SourceEvent{Type: "update", Object: s2, OldObject: s1})
+
+			wm.watchers = append(wm.watchers, secrets, configMaps)
+			handler = NewAPIHandler(ctrl.Log, wm, elected)
+			close(elected)
+		})
+
+		It("should serve the statistics", func() {
+			rec := serve(http.MethodGet, "/api/v1/statistics")
+			Expect(rec.Code).Should(Equal(http.StatusOK))
+			var statistics []*watch.Statistics
+			Expect(json.Unmarshal(rec.Body.Bytes(), &statistics)).Should(Succeed())
+			Expect(statistics).Should(HaveLen(2))
+			// Sorted by the GroupVersionKind
+			Expect(statistics[0].GroupVersionKind.Kind).Should(Equal("ConfigMap"))
+			Expect(statistics[0].Namespaces).Should(HaveLen(2))
+			Expect(statistics[0].Namespaces["default"].Resources["test"]).Should(PointTo(MatchFields(IgnoreExtras, Fields{"AddCount": Equal(1), "UpdateCount": Equal(3)})))
+			Expect(statistics[0].Namespaces["kube-system"].Resources["other"]).Should(PointTo(MatchFields(IgnoreExtras, Fields{"AddCount": Equal(1), "DeleteCount": Equal(1)})))
+			Expect(statistics[1].GroupVersionKind.Kind).Should(Equal("Secret"))
+			Expect(statistics[1].Namespaces["default"].Resources["token"]).Should(PointTo(MatchFields(IgnoreExtras, Fields{"UpdateCount": Equal(1)})))
+
+			rec = serve(http.MethodGet, "/api/v1/statistics?gvk=configmaps&namespace=kube-system")
+			Expect(rec.Code).Should(Equal(http.StatusOK))
+			statistics = nil
+			Expect(json.Unmarshal(rec.Body.Bytes(), &statistics)).Should(Succeed())
+			Expect(statistics).Should(HaveLen(1))
+			Expect(statistics[0].GroupVersionKind.Kind).Should(Equal("ConfigMap"))
+			Expect(statistics[0].Namespaces).Should(HaveLen(1))
+			Expect(statistics[0].Namespaces).Should(HaveKey("kube-system"))
+		})
+
+		It("should serve the resources sorted by the number of updates", func() {
+			rec := serve(http.MethodGet, "/api/v1/top")
+			Expect(rec.Code).Should(Equal(http.StatusOK))
+			var top []*watch.TopResource
+			Expect(json.Unmarshal(rec.Body.Bytes(), &top)).Should(Succeed())
+			Expect(top).Should(HaveLen(3))
+			Expect(top[0].GroupVersionKind.Kind).Should(Equal("ConfigMap"))
+			Expect(top[0].Namespace).Should(Equal("default"))
+			Expect(top[0].Name).Should(Equal("test"))
+			Expect(top[0].UpdateCount).Should(Equal(3))
+			Expect(top[1].Name).Should(Equal("token"))
+			Expect(top[1].UpdateCount).Should(Equal(1))
+			Expect(top[2].Name).Should(Equal("other"))
+			Expect(top[2].UpdateCount).Should(Equal(0))
+
+			rec = serve(http.MethodGet, "/api/v1/top?gvk=configmaps&namespace=default&limit=1")
+			Expect(rec.Code).Should(Equal(http.StatusOK))
+			top = nil
+			Expect(json.Unmarshal(rec.Body.Bytes(), &top)).Should(Succeed())
+			Expect(top).Should(HaveLen(1))
+			Expect(top[0].Name).Should(Equal("test"))
+		})
+
+		It("should serve the updates of a resource for each manager", func() {
+			rec := serve(http.MethodGet, "/api/v1/blame/configmaps/default/test")
+			Expect(rec.Code).Should(Equal(http.StatusOK))
+			blame := &watch.BlameStatistics{}
+			Expect(json.Unmarshal(rec.Body.Bytes(), blame)).Should(Succeed())
+			Expect(blame.Managers).Should(HaveLen(2))
+			Expect(blame.Managers["my-operator"].UpdateCount).Should(Equal(1))
+			Expect(blame.Managers["helm"].UpdateCount).Should(Equal(1))
+			// Unattributed = UpdateCount - the updates attributed to the managers
+			Expect(blame.Unattributed).Should(Equal(1))
+
+			Expect(serve(http.MethodGet, "/api/v1/blame/configmaps/default/unknown").Code).Should(Equal(http.StatusNotFound))
+			Expect(serve(http.MethodGet, "/api/v1/blame/pods/default/test").Code).Should(Equal(http.StatusNotFound))
+			Expect(serve(http.MethodGet, "/api/v1/blame/unknown/default/test").Code).Should(Equal(http.StatusBadRequest))
+		})
+	})
+})
@@ -0,0 +1,163 @@
+package controller
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/zoetrope/kubbernecker/pkg/audit"
+	"k8s.io/apimachinery/pkg/api/meta"
+	auditv1 "k8s.io/apiserver/pkg/apis/audit/v1"
+	"k8s.io/apiserver/pkg/authentication/serviceaccount"
+)
+
+const (
+	// maxAuditRequestBytes is the maximum size of a request body sent by the webhook backend.
+	maxAuditRequestBytes = 10 << 20
+	// otherUsers is the user label value of the users other than service accounts unless CountUsers is enabled.
+	otherUsers = "other"
+)
+
+// AuditReceiverOptions is the options of AuditReceiver.
+type AuditReceiverOptions struct {
+	Addr     string
+	CertFile string
+	KeyFile  string
+	// ClientCAFile is the CA certificates to verify the client certificates of kube-apiserver.
+	ClientCAFile string
+	// CountUsers labels the metrics with every user. By default, only service accounts are labeled,
+	// and the requests of the other users are counted as "other", since every user makes a new series.
+	CountUsers bool
+}
+
+// AuditReceiver receives audit events from the webhook backend of kube-apiserver,
+// and counts the write requests for Kubernetes resources.
+type AuditReceiver struct {
+	logger logr.Logger
+	mapper meta.RESTMapper
+	opts   AuditReceiverOptions
+
+	requests *prometheus.CounterVec
+}
+
+func NewAuditReceiver(logger logr.Logger, mapper meta.RESTMapper, opts AuditReceiverOptions) *AuditReceiver {
+	labels := []string{"group", "version", "kind", "namespace", "verb", "user"}
+	return &AuditReceiver{
+		logger: logger,
+		mapper: mapper,
+		opts:   opts,
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "kubbernecker_audit_write_requests_total",
+			Help: "Total number of write requests for Kubernetes resources recorded in audit events",
+		}, labels),
+	}
+}
+
+func (r *AuditReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	events := &auditv1.EventList{}
+	if err := json.NewDecoder(http.MaxBytesReader(w, req.Body, maxAuditRequestBytes)).Decode(events); err != nil {
+		r.logger.Error(err, "failed to decode audit events")
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "failed to decode audit events", http.StatusBadRequest)
+		return
+	}
+
+	for i := range events.Items {
+		write, ok := audit.ParseWrite(&events.Items[i])
+		if !ok {
+			continue
+		}
+		gvk := audit.KindFor(r.mapper, write.Resource)
+		r.requests.WithLabelValues(gvk.Group, gvk.Version, gvk.Kind, write.Namespace, write.Verb, r.userLabel(write.Username)).Inc()
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// userLabel returns the user label value for the user who sent the request.
+func (r *AuditReceiver) userLabel(username string) string {
+	if r.opts.CountUsers || strings.HasPrefix(username, serviceaccount.ServiceAccountUsernamePrefix) {
+		return username
+	}
+	return otherUsers
+}
+
+// NeedLeaderElection implements LeaderElectionRunnable.
+// kube-apiserver sends audit events to any replicas behind the Service, so all of them have to receive them.
+func (r *AuditReceiver) NeedLeaderElection() bool {
+	return false
+}
+
+// tlsConfig returns the TLS configuration that only accepts the clients with the certificates signed by ClientCAFile.
+func (r *AuditReceiver) tlsConfig() (*tls.Config, error) {
+	if r.opts.CertFile == "" || r.opts.KeyFile == "" || r.opts.ClientCAFile == "" {
+		return nil, errors.New("the audit webhook endpoint requires a certificate, a private key and a client CA file")
+	}
+	cert, err := tls.LoadX509KeyPair(r.opts.CertFile, r.opts.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the certificate: %w", err)
+	}
+	caData, err := os.ReadFile(r.opts.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", r.opts.ClientCAFile, err)
+	}
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(caData) {
+		return nil, fmt.Errorf("no certificates found in %s", r.opts.ClientCAFile)
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+func (r *AuditReceiver) Start(ctx context.Context) error {
+	tlsConfig, err := r.tlsConfig()
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/audit", r)
+	server := &http.Server{
+		Addr:              r.opts.Addr,
+		Handler:           mux,
+		TLSConfig:         tlsConfig,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			r.logger.Error(err, "failed to shutdown audit webhook server")
+		}
+	}()
+
+	r.logger.Info("start audit webhook server", "addr", r.opts.Addr)
+	err = server.ListenAndServeTLS("", "")
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,258 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/zoetrope/kubbernecker/pkg/audit"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const sampleEventList = `{
+  "kind": "EventList",
+  "apiVersion": "audit.k8s.io/v1",
+  "metadata": {},
+  "items": [
+    {
+      "level": "Metadata",
+      "auditID": "1",
+      "stage": "ResponseComplete",
+      "requestURI": "/api/v1/namespaces/default/configmaps/test",
+      "verb": "update",
+      "user": {"username": "system:serviceaccount:default:operator"},
+      "userAgent": "manager/v0.0.0",
+      "objectRef": {"resource": "configmaps", "namespace": "default", "name": "test", "apiVersion": "v1"},
+      "responseStatus": {"metadata": {}, "code": 200},
+      "requestReceivedTimestamp": "2023-02-17T13:25:20.000000Z",
+      "stageTimestamp": "2023-02-17T13:25:20.100000Z"
+    },
+    {
+      "level": "Metadata",
+      "auditID": "2",
+      "stage": "ResponseComplete",
+      "requestURI": "/api/v1/namespaces/default/configmaps/test",
+      "verb": "update",
+      "user": {"username": "system:serviceaccount:default:operator"},
+      "userAgent": "manager/v0.0.0",
+      "objectRef": {"resource": "configmaps", "namespace": "default", "name": "test", "apiVersion": "v1"},
+      "responseStatus": {"metadata": {}, "code": 200},
+      "requestReceivedTimestamp": "2023-02-17T13:25:21.000000Z",
+      "stageTimestamp": "2023-02-17T13:25:21.100000Z"
+    },
+    {
+      "level": "Metadata",
+      "auditID": "3",
+      "stage": "ResponseComplete",
+      "requestURI": "/api/v1/namespaces/default/configmaps/test",
+      "verb": "get",
+      "user": {"username": "alice"},
+      "userAgent": "kubectl/v1.26.0",
+      "objectRef": {"resource": "configmaps", "namespace": "default", "name": "test", "apiVersion": "v1"},
+      "responseStatus": {"metadata": {}, "code": 200},
+      "requestReceivedTimestamp": "2023-02-17T13:25:22.000000Z",
+      "stageTimestamp": "2023-02-17T13:25:22.100000Z"
+    },
+    {
+      "level": "Metadata",
+      "auditID": "4",
+      "stage": "ResponseComplete",
+      "requestURI": "/api/v1/namespaces/default/configmaps/test",
+      "verb": "delete",
+      "user": {"username": "alice"},
+      "userAgent": "kubectl/v1.26.0",
+      "objectRef": {"resource": "configmaps", "namespace": "default", "name": "test", "apiVersion": "v1"},
+      "responseStatus": {"metadata": {}, "code": 200},
+      "requestReceivedTimestamp": "2023-02-17T13:25:23.000000Z",
+      "stageTimestamp": "2023-02-17T13:25:23.100000Z"
+    }
+  ]
+}`
+
+var _ = Describe("Test AuditReceiver", func() {
+	var receiver *AuditReceiver
+	var server *httptest.Server
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Context("with all the users", func() {
+		BeforeEach(func() {
+			receiver = NewAuditReceiver(ctrl.Log.WithName("audit-test"), audit.DefaultRESTMapper(), AuditReceiverOptions{CountUsers: true})
+			server = httptest.NewServer(receiver)
+		})
+
+		It("should count write requests for each user and verb", func() {
+			res, err := http.Post(server.URL, "application/json", bytes.NewBufferString(sampleEventList))
+			Expect(err).ShouldNot(HaveOccurred())
+			res.Body.Close()
+			Expect(res.StatusCode).Should(Equal(http.StatusOK))
+
+			Expect(testutil.CollectAndCount(receiver.requests)).Should(Equal(2))
+			Expect(testutil.ToFloat64(receiver.requests.WithLabelValues("", "v1", "ConfigMap", "default", "update", "system:serviceaccount:default:operator"))).Should(Equal(2.0))
+			Expect(testutil.ToFloat64(receiver.requests.WithLabelValues("", "v1", "ConfigMap", "default", "delete", "alice"))).Should(Equal(1.0))
+		})
+	})
+
+	Context("with the default options", func() {
+		BeforeEach(func() {
+			receiver = NewAuditReceiver(ctrl.Log.WithName("audit-test"), audit.DefaultRESTMapper(), AuditReceiverOptions{})
+			server = httptest.NewServer(receiver)
+		})
+
+		It("should count write requests for each service account and the other users together", func() {
+			res, err := http.Post(server.URL, "application/json", bytes.NewBufferString(sampleEventList))
+			Expect(err).ShouldNot(HaveOccurred())
+			res.Body.Close()
+			Expect(res.StatusCode).Should(Equal(http.StatusOK))
+
+			Expect(testutil.CollectAndCount(receiver.requests)).Should(Equal(2))
+			Expect(testutil.ToFloat64(receiver.requests.WithLabelValues("", "v1", "ConfigMap", "default", "update", "system:serviceaccount:default:operator"))).Should(Equal(2.0))
+			Expect(testutil.ToFloat64(receiver.requests.WithLabelValues("", "v1", "ConfigMap", "default", "delete", "other"))).Should(Equal(1.0))
+		})
+
+		It("should reject invalid requests", func() {
+			res, err := http.Post(server.URL, "application/json", bytes.NewBufferString("invalid"))
+			Expect(err).ShouldNot(HaveOccurred())
+			res.Body.Close()
+			Expect(res.StatusCode).Should(Equal(http.StatusBadRequest))
+
+			res, err = http.Get(server.URL)
+			Expect(err).ShouldNot(HaveOccurred())
+			res.Body.Close()
+			Expect(res.StatusCode).Should(Equal(http.StatusMethodNotAllowed))
+
+			body := `{"items": [` + strings.Repeat(" ", maxAuditRequestBytes) + `]}`
+			res, err = http.Post(server.URL, "application/json", strings.NewReader(body))
+			Expect(err).ShouldNot(HaveOccurred())
+			res.Body.Close()
+			Expect(res.StatusCode).Should(Equal(http.StatusRequestEntityTooLarge))
+
+			Expect(testutil.CollectAndCount(receiver.requests)).Should(Equal(0))
+		})
+	})
+
+	Context("with TLS", func() {
+		var dir string
+		var clientCert tls.Certificate
+		var serverCAs *x509.CertPool
+
+		BeforeEach(func() {
+			dir = GinkgoT().TempDir()
+			serverCA, serverCAKey := newTestCA("server-ca")
+			clientCA, clientCAKey := newTestCA("client-ca")
+			writeTestCert(filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), newTestCert("127.0.0.1", serverCA, serverCAKey))
+			writeTestCert(filepath.Join(dir, "ca.crt"), "", tls.Certificate{Certificate: [][]byte{clientCA.Raw}})
+			clientCert = newTestCert("kube-apiserver", clientCA, clientCAKey)
+			serverCAs = x509.NewCertPool()
+			serverCAs.AddCert(serverCA)
+
+			receiver = NewAuditReceiver(ctrl.Log.WithName("audit-test"), audit.DefaultRESTMapper(), AuditReceiverOptions{
+				CertFile:     filepath.Join(dir, "tls.crt"),
+				KeyFile:      filepath.Join(dir, "tls.key"),
+				ClientCAFile: filepath.Join(dir, "ca.crt"),
+			})
+			tlsConfig, err := receiver.tlsConfig()
+			Expect(err).ShouldNot(HaveOccurred())
+			server = httptest.NewUnstartedServer(receiver)
+			server.TLS = tlsConfig
+			server.StartTLS()
+		})
+
+		It("should only accept the clients with the certificates signed by the client CA", func() {
+			newClient := func(certs ...tls.Certificate) *http.Client {
+				return &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+					RootCAs:      serverCAs,
+					Certificates: certs,
+				}}}
+			}
+
+			_, err := newClient().Post(server.URL, "application/json", bytes.NewBufferString(sampleEventList))
+			Expect(err).Should(HaveOccurred())
+
+			res, err := newClient(clientCert).Post(server.URL, "application/json", bytes.NewBufferString(sampleEventList))
+			Expect(err).ShouldNot(HaveOccurred())
+			res.Body.Close()
+			Expect(res.StatusCode).Should(Equal(http.StatusOK))
+			Expect(testutil.CollectAndCount(receiver.requests)).Should(Equal(2))
+		})
+
+		It("should refuse to start without the client CA", func() {
+			receiver := NewAuditReceiver(ctrl.Log.WithName("audit-test"), audit.DefaultRESTMapper(), AuditReceiverOptions{
+				Addr:     "127.0.0.1:0",
+				CertFile: filepath.Join(dir, "tls.crt"),
+				KeyFile:  filepath.Join(dir, "tls.key"),
+			})
+			Expect(receiver.Start(context.Background())).ShouldNot(Succeed())
+		})
+	})
+})
+
+func newTestCA(name string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).ShouldNot(HaveOccurred())
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).ShouldNot(HaveOccurred())
+	cert, err := x509.ParseCertificate(der)
+	Expect(err).ShouldNot(HaveOccurred())
+	return cert, key
+}
+
+func newTestCert(name string, ca *x509.Certificate, caKey *ecdsa.PrivateKey) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).ShouldNot(HaveOccurred())
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if ip := net.ParseIP(name); ip != nil {
+		template.IPAddresses = []net.IP{ip}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	Expect(err).ShouldNot(HaveOccurred())
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// writeTestCert writes the certificate and its private key in PEM. The key is not written if keyFile is empty.
+func writeTestCert(certFile, keyFile string, cert tls.Certificate) {
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]})
+	Expect(os.WriteFile(certFile, certPEM, 0600)).Should(Succeed())
+	if keyFile == "" {
+		return
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	Expect(err).ShouldNot(HaveOccurred())
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+	Expect(os.WriteFile(keyFile, keyPEM, 0600)).Should(Succeed())
+}
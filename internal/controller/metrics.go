@@ -1,49 +1,109 @@
 package controller
 
 import (
+	"context"
+
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/zoetrope/kubbernecker/pkg/client"
+	"github.com/zoetrope/kubbernecker/pkg/watch"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
 )
 
 var (
-	resourceEventsCountDesc = prometheus.NewDesc(
-		"kubbernecker_resource_events_total",
-		"Total number of events for Kubernetes resources",
-		[]string{"group", "version", "kind", "namespace", "event_type", "resource_name"}, nil)
+	discoveryFailedGroupsDesc = prometheus.NewDesc(
+		"kubbernecker_discovery_failed_groups",
+		"API groups that failed to be discovered. The value is 1 while the discovery is failing",
+		[]string{"group", "version"}, nil)
+	forbiddenResourcesDesc = prometheus.NewDesc(
+		"kubbernecker_forbidden_resources",
+		"Resources that are not watched due to lack of permissions. The value is 1 while the access is forbidden",
+		[]string{"group", "version", "kind"}, nil)
 )
 
 func (m *WatcherManager) Describe(ch chan<- *prometheus.Desc) {
-	ch <- resourceEventsCountDesc
+	m.collector.Describe(ch)
+	ch <- discoveryFailedGroupsDesc
+	ch <- forbiddenResourcesDesc
 }
 
 func (m *WatcherManager) Collect(ch chan<- prometheus.Metric) {
-	for _, watcher := range m.watchers {
-		statistics := watcher.Statistics()
-		for ns, nsStatistics := range statistics.Namespaces {
-			for res, resStatistics := range nsStatistics.Resources {
-				ch <- prometheus.MustNewConstMetric(
-					resourceEventsCountDesc,
-					prometheus.CounterValue,
-					float64(resStatistics.UpdateCount),
-					statistics.GroupVersionKind.Group, statistics.GroupVersionKind.Version, statistics.GroupVersionKind.Kind, ns, "update", res,
-				)
-				ch <- prometheus.MustNewConstMetric(
-					resourceEventsCountDesc,
-					prometheus.CounterValue,
-					float64(resStatistics.AddCount),
-					statistics.GroupVersionKind.Group, statistics.GroupVersionKind.Version, statistics.GroupVersionKind.Kind, ns, "add", res,
-				)
-				ch <- prometheus.MustNewConstMetric(
-					resourceEventsCountDesc,
-					prometheus.CounterValue,
-					float64(resStatistics.DeleteCount),
-					statistics.GroupVersionKind.Group, statistics.GroupVersionKind.Version, statistics.GroupVersionKind.Kind, ns, "delete", res,
-				)
-			}
-		}
+	m.collector.Collect(ch)
+
+	for gv := range m.FailedGroups() {
+		ch <- prometheus.MustNewConstMetric(discoveryFailedGroupsDesc, prometheus.GaugeValue, 1, gv.Group, gv.Version)
+	}
+	for gvk := range m.Forbidden() {
+		ch <- prometheus.MustNewConstMetric(forbiddenResourcesDesc, prometheus.GaugeValue, 1, gvk.Group, gvk.Version, gvk.Kind)
 	}
 }
 
+// registerOTLPMetrics creates the instruments exporting the same statistics as the Prometheus metrics via OTLP,
+// and the number of updates by each manager.
+func (m *WatcherManager) registerOTLPMetrics(meter metric.Meter) (metric.Registration, error) {
+	events, err := meter.Int64ObservableCounter("kubbernecker.resource.events",
+		metric.WithDescription("Total number of events for Kubernetes resources"),
+		metric.WithUnit("{event}"))
+	if err != nil {
+		return nil, err
+	}
+	managerUpdates, err := meter.Int64ObservableCounter("kubbernecker.resource.manager.updates",
+		metric.WithDescription("Total number of updates of Kubernetes resources performed by each manager"),
+		metric.WithUnit("{update}"))
+	if err != nil {
+		return nil, err
+	}
+	managerSwitches, err := meter.Int64ObservableCounter("kubbernecker.resource.manager.switches",
+		metric.WithDescription("Total number of times the manager that updates each Kubernetes resource has changed"),
+		metric.WithUnit("{switch}"))
+	if err != nil {
+		return nil, err
+	}
+
+	return meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		for _, watcher := range m.Watchers() {
+			gvk := watcher.GroupVersionKind()
+			watcher.Range(func(ns, name string, s *watch.ResourceStatistics) bool {
+				for eventType, count := range map[string]int{"add": s.AddCount, "update": s.UpdateCount, "delete": s.DeleteCount} {
+					o.ObserveInt64(events, int64(count), metric.WithAttributes(
+						attribute.String("group", gvk.Group),
+						attribute.String("version", gvk.Version),
+						attribute.String("kind", gvk.Kind),
+						attribute.String("namespace", ns),
+						attribute.String("event_type", eventType),
+						attribute.String("resource_name", name),
+					))
+				}
+				return true
+			})
+			watcher.RangeManagers(func(ns, name string, managers map[string]int, switches int) bool {
+				resource := []attribute.KeyValue{
+					attribute.String("group", gvk.Group),
+					attribute.String("version", gvk.Version),
+					attribute.String("kind", gvk.Kind),
+					attribute.String("namespace", ns),
+					attribute.String("resource_name", name),
+				}
+				for manager, count := range managers {
+					o.ObserveInt64(managerUpdates, int64(count), metric.WithAttributes(append(resource, attribute.String("manager", manager))...))
+				}
+				o.ObserveInt64(managerSwitches, int64(switches), metric.WithAttributes(resource...))
+				return true
+			})
+		}
+		return nil
+	}, events, managerUpdates, managerSwitches)
+}
+
 func SetupMetrics(m *WatcherManager) error {
 	return metrics.Registry.Register(m)
 }
+
+func SetupAuditMetrics(r *AuditReceiver) error {
+	return metrics.Registry.Register(r.requests)
+}
+
+func SetupClientMetrics(l *client.RateLimiter) error {
+	return metrics.Registry.Register(l)
+}
@@ -0,0 +1,339 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/zoetrope/kubbernecker/pkg/config"
+	"github.com/zoetrope/kubbernecker/pkg/watch"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	// NotificationUpdateThreshold is the reason of the notifications sent when the number of updates reaches the threshold.
+	NotificationUpdateThreshold = "UpdateThreshold"
+	// NotificationManagerConflict is the reason of the notifications sent when managers overwrite each other's changes.
+	NotificationManagerConflict = "ManagerConflict"
+
+	notifierCheckInterval     = 30 * time.Second
+	defaultMaxRetries         = 5
+	defaultNotificationWindow = time.Hour
+)
+
+// Notification is the payload sent to webhooks.
+type Notification struct {
+	Reason      string         `json:"reason"`
+	Message     string         `json:"message"`
+	Cluster     string         `json:"cluster,omitempty"`
+	Group       string         `json:"group"`
+	Version     string         `json:"version"`
+	Kind        string         `json:"kind"`
+	Namespace   string         `json:"namespace,omitempty"`
+	Name        string         `json:"name"`
+	UpdateCount int            `json:"updateCount"`
+	Managers    map[string]int `json:"managers,omitempty"`
+	Time        time.Time      `json:"time"`
+
+	key resourceKey
+	// webhooks are the indices of the webhooks to which the notification has not been delivered yet
+	webhooks []int
+}
+
+// statisticsSource provides the statistics of the watched resources, which is implemented by watch.Watcher.
+type statisticsSource interface {
+	GroupVersionKind() schema.GroupVersionKind
+	Cluster() string
+	Range(fn func(namespace, name string, statistics *watch.ResourceStatistics) bool)
+	RangeManagers(fn func(namespace, name string, managers map[string]int, switches int) bool)
+	ManagerUpdates(namespace, name string) map[string]int
+}
+
+type webhook struct {
+	url      string
+	template *template.Template
+}
+
+// Notifier sends notifications to webhooks when the number of updates of a resource reaches the threshold
+// or a manager conflict is detected.
+type Notifier struct {
+	logger   logr.Logger
+	wm       *WatcherManager
+	client   *http.Client
+	config   config.Notifications
+	webhooks []webhook
+	backoff  wait.Backoff
+	window   time.Duration
+
+	mu     sync.Mutex
+	states map[resourceKey]map[string]*notificationState
+}
+
+// notificationState holds the counts of a resource in the current window for a reason.
+type notificationState struct {
+	// start is the time when the current window started
+	start time.Time
+	// base is the count at the start of the current window
+	base int
+	// sending is true while the notification is being sent
+	sending bool
+	// delivered holds the indices of the webhooks to which the notification has been delivered in the current window
+	delivered map[int]bool
+}
+
+func NewNotifier(logger logr.Logger, wm *WatcherManager, cfg config.Notifications) (*Notifier, error) {
+	webhooks := make([]webhook, 0, len(cfg.Webhooks))
+	for _, w := range cfg.Webhooks {
+		tmpl, err := w.ParseTemplate()
+		if err != nil {
+			return nil, fmt.Errorf("invalid template for %s: %w", w.URL, err)
+		}
+		webhooks = append(webhooks, webhook{url: w.URL, template: tmpl})
+	}
+	maxRetries := defaultMaxRetries
+	if cfg.MaxRetries != nil {
+		maxRetries = *cfg.MaxRetries
+	}
+	window := defaultNotificationWindow
+	if cfg.Window != nil {
+		window = cfg.Window.Duration
+	}
+
+	return &Notifier{
+		logger:   logger,
+		wm:       wm,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		config:   cfg,
+		webhooks: webhooks,
+		backoff: wait.Backoff{
+			Duration: time.Second,
+			Factor:   2,
+			Jitter:   0.1,
+			Steps:    maxRetries + 1,
+			Cap:      time.Minute,
+		},
+		window: window,
+		states: make(map[resourceKey]map[string]*notificationState),
+	}, nil
+}
+
+func (n *Notifier) Start(ctx context.Context) error {
+	ticker := time.NewTicker(notifierCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			watchers := n.wm.Watchers()
+			sources := make([]statisticsSource, 0, len(watchers))
+			for _, watcher := range watchers {
+				sources = append(sources, watcher)
+			}
+			for _, notification := range n.check(now, sources) {
+				notification := notification
+				go func() {
+					delivered, _ := n.send(ctx, &notification)
+					n.finish(notification.key, notification.Reason, delivered)
+				}()
+			}
+		}
+	}
+}
+
+// check returns the notifications for the resources that have crossed the thresholds in the current window.
+func (n *Notifier) check(now time.Time, watchers []statisticsSource) []Notification {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	var notifications []Notification
+	seen := make(map[resourceKey]bool)
+	for _, watcher := range watchers {
+		gvk := watcher.GroupVersionKind()
+		// Only the counts are copied, because the callbacks of Range must not block or call the methods of the watcher
+		updates := make(map[resourceKey]int)
+		watcher.Range(func(ns, name string, statistics *watch.ResourceStatistics) bool {
+			updates[resourceKey{gvk: gvk, namespace: ns, name: name}] = statistics.UpdateCount
+			return true
+		})
+		switches := make(map[resourceKey]int)
+		if n.config.ConflictThreshold > 0 {
+			watcher.RangeManagers(func(ns, name string, _ map[string]int, count int) bool {
+				switches[resourceKey{gvk: gvk, namespace: ns, name: name}] = count
+				return true
+			})
+		}
+
+		for key, updateCount := range updates {
+			seen[key] = true
+			ns, name := key.namespace, key.name
+			notification := Notification{
+				Cluster:     watcher.Cluster(),
+				Group:       gvk.Group,
+				Version:     gvk.Version,
+				Kind:        gvk.Kind,
+				Namespace:   ns,
+				Name:        name,
+				UpdateCount: updateCount,
+				Time:        now,
+				key:         key,
+			}
+
+			if n.config.UpdateThreshold > 0 {
+				count, webhooks := n.shouldNotify(now, key, NotificationUpdateThreshold, updateCount, n.config.UpdateThreshold)
+				if len(webhooks) > 0 {
+					notification.webhooks = webhooks
+					notification.Reason = NotificationUpdateThreshold
+					notification.Message = fmt.Sprintf("%s %s has been updated %d times within %s (threshold: %d)", gvk.Kind, objectName(ns, name), count, n.window, n.config.UpdateThreshold)
+					notification.Managers = watcher.ManagerUpdates(ns, name)
+					notifications = append(notifications, notification)
+				}
+			}
+			if n.config.ConflictThreshold > 0 {
+				count, webhooks := n.shouldNotify(now, key, NotificationManagerConflict, switches[key], n.config.ConflictThreshold)
+				if len(webhooks) > 0 {
+					notification.webhooks = webhooks
+					notification.Reason = NotificationManagerConflict
+					notification.Message = fmt.Sprintf("Managers of %s %s have overwritten each other's changes %d times within %s (threshold: %d)", gvk.Kind, objectName(ns, name), count, n.window, n.config.ConflictThreshold)
+					notification.Managers = watcher.ManagerUpdates(ns, name)
+					notifications = append(notifications, notification)
+				}
+			}
+		}
+	}
+	// Forget the deleted resources
+	for key := range n.states {
+		if !seen[key] {
+			delete(n.states, key)
+		}
+	}
+	return notifications
+}
+
+// shouldNotify returns the count in the current window, and the indices of the webhooks to which the notification
+// should be sent if the count has reached the threshold. The webhooks that have already received the notification
+// in the current window are excluded. The count observed for the first time (e.g. restored from the snapshot)
+// is regarded as the base, and the window is restarted with the current count when it expires. The lock has to be held.
+func (n *Notifier) shouldNotify(now time.Time, key resourceKey, reason string, count, threshold int) (int, []int) {
+	if _, ok := n.states[key]; !ok {
+		n.states[key] = make(map[string]*notificationState)
+	}
+	state, ok := n.states[key][reason]
+	if !ok {
+		n.states[key][reason] = &notificationState{start: now, base: count}
+		return 0, nil
+	}
+	if now.Sub(state.start) >= n.window || count < state.base {
+		state.start = now
+		state.base = count
+		state.delivered = nil
+	}
+
+	count -= state.base
+	if state.sending || count < threshold {
+		return count, nil
+	}
+	var webhooks []int
+	for i := range n.webhooks {
+		if !state.delivered[i] {
+			webhooks = append(webhooks, i)
+		}
+	}
+	state.sending = len(webhooks) > 0
+	return count, webhooks
+}
+
+// finish records the webhooks to which the notification has been delivered.
+// The notification is sent again to the other webhooks at the next check.
+func (n *Notifier) finish(key resourceKey, reason string, delivered []int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	state, ok := n.states[key][reason]
+	if !ok {
+		return
+	}
+	state.sending = false
+	if state.delivered == nil {
+		state.delivered = make(map[int]bool)
+	}
+	for _, i := range delivered {
+		state.delivered[i] = true
+	}
+}
+
+// send posts the notification to its webhooks. Failed requests are retried with exponential backoff.
+// It returns the indices of the webhooks to which the notification has been delivered,
+// and the last error if it could not be delivered to some of them.
+func (n *Notifier) send(ctx context.Context, notification *Notification) ([]int, error) {
+	var delivered []int
+	var lastErr error
+	for _, i := range notification.webhooks {
+		hook := n.webhooks[i]
+		body, err := n.render(hook, notification)
+		if err != nil {
+			n.logger.Error(err, "failed to render notification", "url", hook.url)
+			lastErr = err
+			continue
+		}
+
+		backoff := n.backoff
+		err = wait.ExponentialBackoffWithContext(ctx, backoff, func() (bool, error) {
+			err := n.post(ctx, hook.url, body)
+			if err != nil {
+				n.logger.Error(err, "failed to send notification, retrying", "url", hook.url)
+				return false, nil
+			}
+			return true, nil
+		})
+		if err != nil {
+			n.logger.Error(err, "gave up sending notification", "url", hook.url, "reason", notification.Reason)
+			lastErr = err
+			continue
+		}
+		delivered = append(delivered, i)
+	}
+	return delivered, lastErr
+}
+
+func (n *Notifier) render(hook webhook, notification *Notification) ([]byte, error) {
+	if hook.template == nil {
+		return json.Marshal(notification)
+	}
+	buf := &bytes.Buffer{}
+	if err := hook.template.Execute(buf, notification); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (n *Notifier) post(ctx context.Context, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code: %d", res.StatusCode)
+	}
+	return nil
+}
+
+func objectName(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + "/" + name
+}
@@ -0,0 +1,234 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/zoetrope/kubbernecker/pkg/config"
+	"github.com/zoetrope/kubbernecker/pkg/watch"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+type fakeStatisticsSource struct {
+	updates  int
+	switches int
+}
+
+func (f *fakeStatisticsSource) GroupVersionKind() schema.GroupVersionKind {
+	return schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+}
+
+func (f *fakeStatisticsSource) Cluster() string {
+	return ""
+}
+
+func (f *fakeStatisticsSource) Range(fn func(namespace, name string, statistics *watch.ResourceStatistics) bool) {
+	fn("default", "test", &watch.ResourceStatistics{UpdateCount: f.updates})
+}
+
+func (f *fakeStatisticsSource) RangeManagers(fn func(namespace, name string, managers map[string]int, switches int) bool) {
+	fn("default", "test", f.ManagerUpdates("default", "test"), f.switches)
+}
+
+func (f *fakeStatisticsSource) ManagerUpdates(namespace, name string) map[string]int {
+	return map[string]int{"controller-a": f.updates / 2, "controller-b": f.updates - f.updates/2}
+}
+
+var _ = Describe("Test Notifier", func() {
+	var server *httptest.Server
+	var mu sync.Mutex
+	var bodies []string
+	var failures int
+
+	BeforeEach(func() {
+		bodies = nil
+		failures = 0
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			if failures > 0 {
+				failures--
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			body, _ := io.ReadAll(req.Body)
+			bodies = append(bodies, string(body))
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should notify once per window when the thresholds are crossed", func() {
+		notifier, err := NewNotifier(ctrl.Log.WithName("notifier-test"), nil, config.Notifications{
+			Webhooks:          []config.Webhook{{URL: server.URL}},
+			UpdateThreshold:   10,
+			ConflictThreshold: 3,
+			Window:            &metav1.Duration{Duration: time.Hour},
+		})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		now := time.Now()
+		source := &fakeStatisticsSource{}
+		Expect(notifier.check(now, []statisticsSource{source})).Should(BeEmpty())
+
+		source.updates = 5
+		source.switches = 1
+		Expect(notifier.check(now, []statisticsSource{source})).Should(BeEmpty())
+
+		source.updates = 10
+		notifications := notifier.check(now, []statisticsSource{source})
+		Expect(notifications).Should(HaveLen(1))
+		Expect(notifications[0].Reason).Should(Equal(NotificationUpdateThreshold))
+		Expect(notifications[0].Name).Should(Equal("test"))
+		Expect(notifications[0].Managers).Should(Equal(map[string]int{"controller-a": 5, "controller-b": 5}))
+		// The notification is not sent again while it is being sent
+		Expect(notifier.check(now, []statisticsSource{source})).Should(BeEmpty())
+		notifier.finish(notifications[0].key, notifications[0].Reason, notifications[0].webhooks)
+
+		source.updates = 20
+		source.switches = 3
+		notifications = notifier.check(now, []statisticsSource{source})
+		Expect(notifications).Should(HaveLen(1))
+		Expect(notifications[0].Reason).Should(Equal(NotificationManagerConflict))
+		notifier.finish(notifications[0].key, notifications[0].Reason, notifications[0].webhooks)
+
+		Expect(notifier.check(now, []statisticsSource{source})).Should(BeEmpty())
+
+		// The counts are reset when the window expires
+		now = now.Add(time.Hour)
+		Expect(notifier.check(now, []statisticsSource{source})).Should(BeEmpty())
+		source.updates = 29
+		Expect(notifier.check(now, []statisticsSource{source})).Should(BeEmpty())
+		source.updates = 30
+		notifications = notifier.check(now, []statisticsSource{source})
+		Expect(notifications).Should(HaveLen(1))
+		Expect(notifications[0].Reason).Should(Equal(NotificationUpdateThreshold))
+		Expect(notifications[0].Message).Should(Equal("ConfigMap default/test has been updated 10 times within 1h0m0s (threshold: 10)"))
+		Expect(notifications[0].UpdateCount).Should(Equal(30))
+	})
+
+	It("should not notify the counts observed for the first time", func() {
+		notifier, err := NewNotifier(ctrl.Log.WithName("notifier-test"), nil, config.Notifications{
+			Webhooks:        []config.Webhook{{URL: server.URL}},
+			UpdateThreshold: 10,
+		})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// e.g. the statistics restored from the snapshot
+		source := &fakeStatisticsSource{updates: 100}
+		Expect(notifier.check(time.Now(), []statisticsSource{source})).Should(BeEmpty())
+		source.updates = 109
+		Expect(notifier.check(time.Now(), []statisticsSource{source})).Should(BeEmpty())
+		source.updates = 110
+		Expect(notifier.check(time.Now(), []statisticsSource{source})).Should(HaveLen(1))
+	})
+
+	It("should notify again only the webhooks to which the notification could not be sent", func() {
+		var downBodies int
+		available := false
+		down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			if !available {
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			downBodies++
+		}))
+		defer down.Close()
+
+		notifier, err := NewNotifier(ctrl.Log.WithName("notifier-test"), nil, config.Notifications{
+			Webhooks:        []config.Webhook{{URL: server.URL}, {URL: down.URL}},
+			UpdateThreshold: 10,
+			MaxRetries:      pointer.Int(0),
+		})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		source := &fakeStatisticsSource{}
+		Expect(notifier.check(time.Now(), []statisticsSource{source})).Should(BeEmpty())
+		source.updates = 10
+		notifications := notifier.check(time.Now(), []statisticsSource{source})
+		Expect(notifications).Should(HaveLen(1))
+		Expect(notifications[0].webhooks).Should(Equal([]int{0, 1}))
+		delivered, err := notifier.send(context.Background(), &notifications[0])
+		Expect(err).Should(HaveOccurred())
+		Expect(delivered).Should(Equal([]int{0}))
+		notifier.finish(notifications[0].key, notifications[0].Reason, delivered)
+
+		notifications = notifier.check(time.Now(), []statisticsSource{source})
+		Expect(notifications).Should(HaveLen(1))
+		Expect(notifications[0].webhooks).Should(Equal([]int{1}))
+		mu.Lock()
+		available = true
+		mu.Unlock()
+		delivered, err = notifier.send(context.Background(), &notifications[0])
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(delivered).Should(Equal([]int{1}))
+		notifier.finish(notifications[0].key, notifications[0].Reason, delivered)
+
+		Expect(notifier.check(time.Now(), []statisticsSource{source})).Should(BeEmpty())
+		mu.Lock()
+		defer mu.Unlock()
+		Expect(bodies).Should(HaveLen(1))
+		Expect(downBodies).Should(Equal(1))
+	})
+
+	It("should send notifications rendered with the template and retry on failures", func() {
+		notifier, err := NewNotifier(ctrl.Log.WithName("notifier-test"), nil, config.Notifications{
+			Webhooks: []config.Webhook{
+				{URL: server.URL},
+				{URL: server.URL, Template: `{"text": "{{ .Message }}"}`},
+				{URL: server.URL, Template: `{"text": {{ json .Message }}, "managers": {{ json .Managers }}}`},
+			},
+			MaxRetries: pointer.Int(2),
+		})
+		Expect(err).ShouldNot(HaveOccurred())
+		notifier.backoff.Duration = 10 * time.Millisecond
+		failures = 2
+
+		delivered, err := notifier.send(context.Background(), &Notification{Reason: NotificationUpdateThreshold, Message: `"test" updated`, Kind: "ConfigMap", Name: "test", webhooks: []int{0, 1, 2}})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(delivered).Should(Equal([]int{0, 1, 2}))
+
+		mu.Lock()
+		defer mu.Unlock()
+		Expect(bodies).Should(HaveLen(3))
+		notification := &Notification{}
+		Expect(json.Unmarshal([]byte(bodies[0]), notification)).Should(Succeed())
+		Expect(notification.Reason).Should(Equal(NotificationUpdateThreshold))
+		Expect(notification.Name).Should(Equal("test"))
+		Expect(bodies[1]).Should(Equal(`{"text": ""test" updated"}`))
+		Expect(bodies[2]).Should(Equal(`{"text": "\"test\" updated", "managers": null}`))
+	})
+
+	It("should give up after the max retries", func() {
+		notifier, err := NewNotifier(ctrl.Log.WithName("notifier-test"), nil, config.Notifications{
+			Webhooks:   []config.Webhook{{URL: server.URL}},
+			MaxRetries: pointer.Int(1),
+		})
+		Expect(err).ShouldNot(HaveOccurred())
+		notifier.backoff.Duration = 10 * time.Millisecond
+		failures = 2
+
+		delivered, err := notifier.send(context.Background(), &Notification{Reason: NotificationUpdateThreshold, webhooks: []int{0}})
+		Expect(err).Should(HaveOccurred())
+		Expect(delivered).Should(BeEmpty())
+
+		mu.Lock()
+		defer mu.Unlock()
+		Expect(bodies).Should(BeEmpty())
+		Expect(failures).Should(BeZero())
+	})
+})
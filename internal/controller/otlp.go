@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/zoetrope/kubbernecker/pkg/config"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.20.0"
+)
+
+const (
+	defaultOTLPInterval = time.Minute
+	otlpShutdownTimeout = 10 * time.Second
+	otlpServiceName     = "kubbernecker-metrics"
+	otlpMeterName       = "github.com/zoetrope/kubbernecker"
+)
+
+// OTLPExporter pushes the metrics of the watchers to an OpenTelemetry collector periodically.
+type OTLPExporter struct {
+	logger logr.Logger
+	wm     *WatcherManager
+	config config.OTLP
+}
+
+func NewOTLPExporter(logger logr.Logger, wm *WatcherManager, cfg config.OTLP) *OTLPExporter {
+	return &OTLPExporter{
+		logger: logger,
+		wm:     wm,
+		config: cfg,
+	}
+}
+
+func (e *OTLPExporter) Start(ctx context.Context) error {
+	exporter, err := e.newExporter(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+	attrs := []attribute.KeyValue{semconv.ServiceName(otlpServiceName)}
+	if e.config.ClusterName != "" {
+		attrs = append(attrs, semconv.K8SClusterName(e.config.ClusterName))
+	}
+	res, err := resource.New(ctx, resource.WithFromEnv(), resource.WithTelemetrySDK(), resource.WithAttributes(attrs...))
+	if err != nil {
+		return fmt.Errorf("failed to create OTLP resource: %w", err)
+	}
+	interval := defaultOTLPInterval
+	if e.config.Interval != nil {
+		interval = e.config.Interval.Duration
+	}
+
+	provider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithResource(res),
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter, sdkmetric.WithInterval(interval))),
+	)
+	if _, err := e.wm.registerOTLPMetrics(provider.Meter(otlpMeterName)); err != nil {
+		return fmt.Errorf("failed to register OTLP metrics: %w", err)
+	}
+	e.logger.Info("start exporting metrics via OTLP", "endpoint", e.config.Endpoint, "protocol", e.protocol(), "interval", interval)
+
+	<-ctx.Done()
+	// The metrics since the last export are flushed on shutdown
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), otlpShutdownTimeout)
+	defer cancel()
+	if err := provider.Shutdown(shutdownCtx); err != nil {
+		e.logger.Error(err, "failed to shutdown OTLP exporter")
+	}
+	return nil
+}
+
+func (e *OTLPExporter) protocol() string {
+	if e.config.Protocol == "" {
+		return config.OTLPProtocolGRPC
+	}
+	return e.config.Protocol
+}
+
+func (e *OTLPExporter) newExporter(ctx context.Context) (sdkmetric.Exporter, error) {
+	switch e.protocol() {
+	case config.OTLPProtocolGRPC:
+		opts := []otlpmetricgrpc.Option{otlpmetricgrpc.WithEndpoint(e.config.Endpoint)}
+		if e.config.Insecure {
+			opts = append(opts, otlpmetricgrpc.WithInsecure())
+		}
+		if len(e.config.Headers) != 0 {
+			opts = append(opts, otlpmetricgrpc.WithHeaders(e.config.Headers))
+		}
+		return otlpmetricgrpc.New(ctx, opts...)
+	case config.OTLPProtocolHTTP:
+		opts := []otlpmetrichttp.Option{otlpmetrichttp.WithEndpoint(e.config.Endpoint)}
+		if e.config.Insecure {
+			opts = append(opts, otlpmetrichttp.WithInsecure())
+		}
+		if len(e.config.Headers) != 0 {
+			opts = append(opts, otlpmetrichttp.WithHeaders(e.config.Headers))
+		}
+		return otlpmetrichttp.New(ctx, opts...)
+	}
+	return nil, fmt.Errorf("unknown OTLP protocol %q", e.config.Protocol)
+}
@@ -0,0 +1,191 @@
+package controller
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/zoetrope/kubbernecker/pkg/config"
+	"github.com/zoetrope/kubbernecker/pkg/watch"
+	collectormetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonv1 "go.opentelemetry.io/proto/otlp/common/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// otlpReceiver is a stand-in of an OpenTelemetry collector keeping the data points of the received metrics.
+type otlpReceiver struct {
+	collectormetrics.UnimplementedMetricsServiceServer
+
+	mu sync.Mutex
+	// points holds the values of the data points keyed by the metric name and the attributes
+	points   map[string]int64
+	resource map[string]string
+}
+
+func newOTLPReceiver() *otlpReceiver {
+	return &otlpReceiver{
+		points:   make(map[string]int64),
+		resource: make(map[string]string),
+	}
+}
+
+func (r *otlpReceiver) Export(_ context.Context, req *collectormetrics.ExportMetricsServiceRequest) (*collectormetrics.ExportMetricsServiceResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, rm := range req.ResourceMetrics {
+		for _, attr := range rm.Resource.GetAttributes() {
+			r.resource[attr.Key] = attr.Value.GetStringValue()
+		}
+		for _, sm := range rm.ScopeMetrics {
+			for _, m := range sm.Metrics {
+				for _, dp := range m.GetSum().GetDataPoints() {
+					r.points[m.Name+"{"+formatAttributes(dp.Attributes)+"}"] = dp.GetAsInt()
+				}
+			}
+		}
+	}
+	return &collectormetrics.ExportMetricsServiceResponse{}, nil
+}
+
+func (r *otlpReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	exportReq := &collectormetrics.ExportMetricsServiceRequest{}
+	if err := proto.Unmarshal(body, exportReq); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	resp, _ := r.Export(req.Context(), exportReq)
+	data, _ := proto.Marshal(resp)
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	_, _ = w.Write(data)
+}
+
+func (r *otlpReceiver) Points() map[string]int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result := make(map[string]int64, len(r.points))
+	for k, v := range r.points {
+		result[k] = v
+	}
+	return result
+}
+
+func (r *otlpReceiver) Resource() map[string]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result := make(map[string]string, len(r.resource))
+	for k, v := range r.resource {
+		result[k] = v
+	}
+	return result
+}
+
+// formatAttributes formats the attributes in the order of the keys sent by the SDK, which are sorted.
+func formatAttributes(attrs []*commonv1.KeyValue) string {
+	s := make([]string, 0, len(attrs))
+	for _, attr := range attrs {
+		s = append(s, attr.Key+"="+attr.Value.GetStringValue())
+	}
+	return strings.Join(s, ",")
+}
+
+var _ = Describe("Test OTLPExporter", func() {
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	base := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+	newMeta := func(resourceVersion, manager string, t time.Time) *metav1.PartialObjectMetadata {
+		return &metav1.PartialObjectMetadata{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:       "default",
+				Name:            "test",
+				ResourceVersion: resourceVersion,
+				ManagedFields: []metav1.ManagedFieldsEntry{
+					{Manager: manager, Operation: metav1.ManagedFieldsOperationUpdate, Time: &metav1.Time{Time: t}},
+				},
+			},
+		}
+	}
+
+	newWatcherManager := func() *WatcherManager {
+		wm := NewWatcherManager(ctrl.Log, nil, &config.Config{}, 0)
+		watcher := watch.NewWatcherWithSource(ctrl.Log, nil, "", gvk, labels.Everything(), false)
+		watcher.OnEvent(&watch.SourceEvent{Type: "add", Object: newMeta("1", "helm", base)})
+		watcher.OnEvent(&watch.SourceEvent{Type: "update", Object: newMeta("2", "my-operator", base.Add(time.Second)), OldObject: newMeta("1", "helm", base)})
+		watcher.OnEvent(&watch.SourceEvent{Type: "update", Object: newMeta("3", "helm", base.Add(2*time.Second)), OldObject: newMeta("2", "my-operator", base.Add(time.Second))})
+		wm.watchers = append(wm.watchers, watcher)
+		return wm
+	}
+
+	export := func(receiver *otlpReceiver, endpoint, protocol string) {
+		ctx, cancel := context.WithCancel(context.Background())
+		exporter := NewOTLPExporter(ctrl.Log, newWatcherManager(), config.OTLP{
+			Endpoint:    endpoint,
+			Protocol:    protocol,
+			Insecure:    true,
+			Interval:    &metav1.Duration{Duration: 100 * time.Millisecond},
+			ClusterName: "test-cluster",
+		})
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			Expect(exporter.Start(ctx)).Should(Succeed())
+		}()
+
+		Eventually(receiver.Points).Should(And(
+			HaveKeyWithValue("kubbernecker.resource.events{event_type=add,group=,kind=ConfigMap,namespace=default,resource_name=test,version=v1}", BeEquivalentTo(1)),
+			HaveKeyWithValue("kubbernecker.resource.events{event_type=update,group=,kind=ConfigMap,namespace=default,resource_name=test,version=v1}", BeEquivalentTo(2)),
+			HaveKeyWithValue("kubbernecker.resource.events{event_type=delete,group=,kind=ConfigMap,namespace=default,resource_name=test,version=v1}", BeEquivalentTo(0)),
+			HaveKeyWithValue("kubbernecker.resource.manager.updates{group=,kind=ConfigMap,manager=helm,namespace=default,resource_name=test,version=v1}", BeEquivalentTo(1)),
+			HaveKeyWithValue("kubbernecker.resource.manager.updates{group=,kind=ConfigMap,manager=my-operator,namespace=default,resource_name=test,version=v1}", BeEquivalentTo(1)),
+			HaveKeyWithValue("kubbernecker.resource.manager.switches{group=,kind=ConfigMap,namespace=default,resource_name=test,version=v1}", BeEquivalentTo(1)),
+		))
+		Expect(receiver.Resource()).Should(And(
+			HaveKeyWithValue("k8s.cluster.name", "test-cluster"),
+			HaveKeyWithValue("service.name", "kubbernecker-metrics"),
+		))
+
+		cancel()
+		Eventually(done).Should(BeClosed())
+	}
+
+	It("should export the metrics via OTLP/gRPC", func() {
+		receiver := newOTLPReceiver()
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).ShouldNot(HaveOccurred())
+		server := grpc.NewServer()
+		collectormetrics.RegisterMetricsServiceServer(server, receiver)
+		go func() {
+			_ = server.Serve(lis)
+		}()
+		defer server.Stop()
+
+		export(receiver, lis.Addr().String(), config.OTLPProtocolGRPC)
+	})
+
+	It("should export the metrics via OTLP/HTTP", func() {
+		receiver := newOTLPReceiver()
+		mux := http.NewServeMux()
+		mux.Handle("/v1/metrics", receiver)
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		export(receiver, strings.TrimPrefix(server.URL, "http://"), config.OTLPProtocolHTTP)
+	})
+})
@@ -0,0 +1,138 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/zoetrope/kubbernecker/pkg/watch"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// defaultPersistenceInterval is the default interval to save the snapshots of the statistics.
+const defaultPersistenceInterval = time.Minute
+
+// loadSnapshots reads the snapshots of the watchers saved in the file.
+// If the file does not exist, no snapshot is returned.
+func loadSnapshots(path string) (map[schema.GroupVersionKind]*watch.Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[schema.GroupVersionKind]*watch.Snapshot{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var list []*watch.Snapshot
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	snapshots := make(map[schema.GroupVersionKind]*watch.Snapshot, len(list))
+	for _, snapshot := range list {
+		if snapshot.Statistics == nil {
+			continue
+		}
+		gvk := snapshot.Statistics.GroupVersionKind
+		snapshots[schema.GroupVersionKind{Group: gvk.Group, Version: gvk.Version, Kind: gvk.Kind}] = snapshot
+	}
+	return snapshots, nil
+}
+
+// saveSnapshots writes the snapshots to the file atomically, so that the file is not corrupted by a crash while writing.
+func saveSnapshots(path string, snapshots []*watch.Snapshot) error {
+	data, err := json.Marshal(snapshots)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// restoreSnapshots loads the snapshots saved by the previous leader. A broken file is ignored to start from scratch.
+func (m *WatcherManager) restoreSnapshots() {
+	if m.config.Persistence == nil {
+		return
+	}
+	snapshots, err := loadSnapshots(m.config.Persistence.Path)
+	if err != nil {
+		m.logger.Error(err, "failed to load snapshots, the statistics are not restored", "path", m.config.Persistence.Path)
+		return
+	}
+	m.logger.Info("loaded snapshots", "path", m.config.Persistence.Path, "resources", len(snapshots))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.snapshots = snapshots
+}
+
+// restoreWatcher restores the statistics of the watcher from the snapshot if any. It has to be called before starting the watcher.
+func (m *WatcherManager) restoreWatcher(watcher *watch.Watcher) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot, ok := m.snapshots[watcher.GroupVersionKind()]
+	if !ok {
+		return
+	}
+	if err := watcher.Restore(snapshot); err != nil {
+		m.logger.Error(err, "failed to restore statistics", "gvk", watcher.GroupVersionKind().String())
+		return
+	}
+	delete(m.snapshots, watcher.GroupVersionKind())
+}
+
+// persist saves the snapshots periodically and when the context is canceled.
+func (m *WatcherManager) persist(ctx context.Context) {
+	interval := defaultPersistenceInterval
+	if m.config.Persistence.Interval != nil {
+		interval = m.config.Persistence.Interval.Duration
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			m.saveSnapshots()
+			return
+		case <-ticker.C:
+			m.saveSnapshots()
+		}
+	}
+}
+
+func (m *WatcherManager) saveSnapshots() {
+	m.mu.RLock()
+	snapshots := make([]*watch.Snapshot, 0, len(m.watchers)+len(m.snapshots))
+	for _, watcher := range m.watchers {
+		snapshots = append(snapshots, watcher.Snapshot())
+	}
+	// Keep the snapshots of the resources that are not watched yet (e.g. the discovery of the group failed)
+	for _, snapshot := range m.snapshots {
+		snapshots = append(snapshots, snapshot)
+	}
+	m.mu.RUnlock()
+
+	if err := saveSnapshots(m.config.Persistence.Path, snapshots); err != nil {
+		m.logger.Error(err, "failed to save snapshots", "path", m.config.Persistence.Path)
+	}
+}
@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/zoetrope/kubbernecker/pkg/config"
+	"github.com/zoetrope/kubbernecker/pkg/watch"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var _ = Describe("Test persistence", func() {
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	newSnapshot := func(kind string, updates int) *watch.Snapshot {
+		return &watch.Snapshot{
+			Statistics: &watch.Statistics{
+				GroupVersionKind: metav1.GroupVersionKind{Version: "v1", Kind: kind},
+				Namespaces: map[string]*watch.NamespaceStatistics{
+					"default": {Resources: map[string]*watch.ResourceStatistics{
+						"test": {UpdateCount: updates},
+					}},
+				},
+			},
+			Managers: map[string]map[string]int{"default/test": {"controller": updates}},
+		}
+	}
+
+	It("should save and load snapshots", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "snapshots.json")
+		snapshots, err := loadSnapshots(path)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(snapshots).Should(BeEmpty())
+
+		Expect(saveSnapshots(path, []*watch.Snapshot{newSnapshot("ConfigMap", 3), newSnapshot("Secret", 1)})).Should(Succeed())
+		snapshots, err = loadSnapshots(path)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(snapshots).Should(HaveLen(2))
+		Expect(snapshots[gvk]).Should(Equal(newSnapshot("ConfigMap", 3)))
+
+		Expect(os.WriteFile(path, []byte("broken"), 0644)).Should(Succeed())
+		_, err = loadSnapshots(path)
+		Expect(err).Should(HaveOccurred())
+	})
+
+	It("should restore watchers and keep the snapshots of resources not watched yet", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "snapshots.json")
+		Expect(saveSnapshots(path, []*watch.Snapshot{newSnapshot("ConfigMap", 3), newSnapshot("Secret", 1)})).Should(Succeed())
+
+		wm := NewWatcherManager(ctrl.Log, nil, &config.Config{Persistence: &config.Persistence{Path: path}}, 0)
+		wm.restoreSnapshots()
+		watcher := watch.NewWatcher(ctrl.Log, nil, gvk, labels.Everything(), labels.Everything(), false)
+		wm.restoreWatcher(watcher)
+		wm.watchers = append(wm.watchers, watcher)
+		Expect(watcher.Statistics().Namespaces["default"].Resources["test"].UpdateCount).Should(Equal(3))
+		Expect(watcher.ManagerUpdates("default", "test")).Should(Equal(map[string]int{"controller": 3}))
+
+		wm.saveSnapshots()
+		snapshots, err := loadSnapshots(path)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(snapshots).Should(HaveKey(gvk))
+		Expect(snapshots).Should(HaveKey(schema.GroupVersionKind{Version: "v1", Kind: "Secret"}))
+	})
+})
@@ -0,0 +1,176 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/zoetrope/kubbernecker/pkg/config"
+	"github.com/zoetrope/kubbernecker/pkg/watch"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	// UpdateStormReason is the reason of the events recorded on resources updated too frequently.
+	UpdateStormReason = "UpdateStorm"
+
+	updateStormCheckInterval = 30 * time.Second
+	updateStormTopManagers   = 3
+)
+
+// resourceKey identifies a resource watched by the watchers.
+type resourceKey struct {
+	gvk       schema.GroupVersionKind
+	namespace string
+	name      string
+}
+
+// storm is a resource whose update rate has exceeded the threshold for the configured duration.
+type storm struct {
+	resourceKey
+	rate     float64
+	duration time.Duration
+}
+
+// UpdateStormDetector detects resources whose update rate exceeds the threshold for a sustained period,
+// and records UpdateStorm events on them.
+type UpdateStormDetector struct {
+	logger   logr.Logger
+	wm       *WatcherManager
+	recorder record.EventRecorder
+	config   config.UpdateStorm
+
+	initialized bool
+	lastCheck   time.Time
+	previous    map[resourceKey]int
+	since       map[resourceKey]time.Time
+	notified    map[resourceKey]bool
+}
+
+func NewUpdateStormDetector(logger logr.Logger, wm *WatcherManager, recorder record.EventRecorder, cfg config.UpdateStorm) *UpdateStormDetector {
+	return &UpdateStormDetector{
+		logger:   logger,
+		wm:       wm,
+		recorder: recorder,
+		config:   cfg,
+		previous: make(map[resourceKey]int),
+		since:    make(map[resourceKey]time.Time),
+		notified: make(map[resourceKey]bool),
+	}
+}
+
+func (d *UpdateStormDetector) Start(ctx context.Context) error {
+	ticker := time.NewTicker(updateStormCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			watchers := d.wm.Watchers()
+			for _, s := range d.detect(now, updateCounts(watchers)) {
+				d.notify(watchers, s)
+			}
+		}
+	}
+}
+
+// updateCounts returns the update counts of the resources watched by the watchers without copying the statistics.
+func updateCounts(watchers []*watch.Watcher) map[resourceKey]int {
+	current := make(map[resourceKey]int)
+	for _, watcher := range watchers {
+		gvk := watcher.GroupVersionKind()
+		watcher.Range(func(ns, name string, statistics *watch.ResourceStatistics) bool {
+			current[resourceKey{gvk: gvk, namespace: ns, name: name}] = statistics.UpdateCount
+			return true
+		})
+	}
+	return current
+}
+
+// detect compares the update counts with the previous check, and returns the resources
+// whose update rate has just exceeded the threshold for the configured duration.
+func (d *UpdateStormDetector) detect(now time.Time, current map[resourceKey]int) []storm {
+	defer func() {
+		d.initialized = true
+		d.lastCheck = now
+	}()
+
+	previous := d.previous
+	d.previous = current
+	if !d.initialized {
+		return nil
+	}
+
+	elapsed := now.Sub(d.lastCheck)
+	var storms []storm
+	for key, count := range current {
+		rate := float64(count-previous[key]) / elapsed.Minutes()
+		if rate < d.config.Threshold {
+			delete(d.since, key)
+			delete(d.notified, key)
+			continue
+		}
+		if _, ok := d.since[key]; !ok {
+			d.since[key] = d.lastCheck
+		}
+		duration := now.Sub(d.since[key])
+		if duration < d.config.Duration.Duration || d.notified[key] {
+			continue
+		}
+		d.notified[key] = true
+		storms = append(storms, storm{resourceKey: key, rate: rate, duration: duration})
+	}
+	for key := range d.since {
+		if _, ok := current[key]; !ok {
+			delete(d.since, key)
+			delete(d.notified, key)
+		}
+	}
+	return storms
+}
+
+func (d *UpdateStormDetector) notify(watchers []*watch.Watcher, s storm) {
+	for _, watcher := range watchers {
+		if watcher.GroupVersionKind() != s.gvk {
+			continue
+		}
+		obj, ok := watcher.Object(s.namespace, s.name)
+		if !ok {
+			return
+		}
+		message := fmt.Sprintf("Updated %.1f times per minute for %s (threshold: %.1f).", s.rate, s.duration.Round(time.Second), d.config.Threshold)
+		if managers := topManagers(watcher.ManagerUpdates(s.namespace, s.name), updateStormTopManagers); managers != "" {
+			message += " Top managers: " + managers
+		}
+		d.logger.Info("update storm detected", "gvk", s.gvk.String(), "namespace", s.namespace, "name", s.name, "rate", s.rate)
+		d.recorder.Event(obj, corev1.EventTypeWarning, UpdateStormReason, message)
+		return
+	}
+}
+
+// topManagers formats the managers that performed the most updates.
+func topManagers(updates map[string]int, n int) string {
+	managers := make([]string, 0, len(updates))
+	for manager := range updates {
+		managers = append(managers, manager)
+	}
+	sort.Slice(managers, func(i, j int) bool {
+		if updates[managers[i]] != updates[managers[j]] {
+			return updates[managers[i]] > updates[managers[j]]
+		}
+		return managers[i] < managers[j]
+	})
+	if len(managers) > n {
+		managers = managers[:n]
+	}
+	for i, manager := range managers {
+		managers[i] = fmt.Sprintf("%s (%d)", manager, updates[manager])
+	}
+	return strings.Join(managers, ", ")
+}
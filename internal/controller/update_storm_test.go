@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/zoetrope/kubbernecker/pkg/config"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var _ = Describe("Test UpdateStormDetector", func() {
+	newStatistics := func(counts map[string]int) map[resourceKey]int {
+		current := make(map[resourceKey]int)
+		for name, count := range counts {
+			current[resourceKey{gvk: schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, namespace: "default", name: name}] = count
+		}
+		return current
+	}
+
+	It("should detect resources updated too frequently for the duration", func() {
+		detector := NewUpdateStormDetector(ctrl.Log.WithName("update-storm-test"), nil, nil, config.UpdateStorm{
+			Threshold: 10,
+			Duration:  metav1.Duration{Duration: 2 * time.Minute},
+		})
+		now := time.Now()
+
+		Expect(detector.detect(now, newStatistics(map[string]int{"hot": 100, "cold": 0}))).Should(BeEmpty())
+
+		now = now.Add(time.Minute)
+		Expect(detector.detect(now, newStatistics(map[string]int{"hot": 120, "cold": 5}))).Should(BeEmpty())
+
+		now = now.Add(time.Minute)
+		storms := detector.detect(now, newStatistics(map[string]int{"hot": 150, "cold": 10}))
+		Expect(storms).Should(HaveLen(1))
+		Expect(storms[0].gvk).Should(Equal(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}))
+		Expect(storms[0].name).Should(Equal("hot"))
+		Expect(storms[0].rate).Should(BeNumerically("~", 30))
+		Expect(storms[0].duration).Should(Equal(2 * time.Minute))
+
+		By("notifying only once while the storm continues")
+		now = now.Add(time.Minute)
+		Expect(detector.detect(now, newStatistics(map[string]int{"hot": 200, "cold": 10}))).Should(BeEmpty())
+
+		By("detecting again after the storm calms down")
+		now = now.Add(time.Minute)
+		Expect(detector.detect(now, newStatistics(map[string]int{"hot": 201, "cold": 10}))).Should(BeEmpty())
+		now = now.Add(time.Minute)
+		Expect(detector.detect(now, newStatistics(map[string]int{"hot": 220, "cold": 10}))).Should(BeEmpty())
+		now = now.Add(time.Minute)
+		Expect(detector.detect(now, newStatistics(map[string]int{"hot": 240, "cold": 10}))).Should(HaveLen(1))
+	})
+
+	It("should format top managers", func() {
+		Expect(topManagers(map[string]int{"a": 1, "b": 5, "c": 3, "d": 3}, 3)).Should(Equal("b (5), c (3), d (3)"))
+		Expect(topManagers(nil, 3)).Should(BeEmpty())
+	})
+})
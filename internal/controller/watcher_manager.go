@@ -2,6 +2,12 @@ package controller
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
@@ -11,30 +17,115 @@ import (
 	"github.com/zoetrope/kubbernecker/pkg/watch"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
 )
 
+// discoveryRetryInterval is the interval to retry discovery of the API groups that failed to be discovered.
+const discoveryRetryInterval = time.Minute
+
 type WatcherManager struct {
-	kube     *client.KubeClient
-	watchers []*watch.Watcher
-	logger   logr.Logger
-	config   *config.Config
+	kube          *client.KubeClient
+	logger        logr.Logger
+	config        *config.Config
+	startInterval time.Duration
+
+	mu           sync.RWMutex
+	initialized  bool
+	watchers     []*watch.Watcher
+	watching     map[schema.GroupVersionKind]bool
+	failedGroups map[schema.GroupVersion]error
+	forbidden    map[schema.GroupVersionKind]string
+	// snapshots holds the persisted statistics of the resources that are not watched yet
+	snapshots map[schema.GroupVersionKind]*watch.Snapshot
+	sink      watch.EventSink
+	collector *watch.Collector
 }
 
-func NewWatcherManager(logger logr.Logger, kubeClient *client.KubeClient, cfg *config.Config) *WatcherManager {
-	return &WatcherManager{
-		logger: logger,
-		kube:   kubeClient,
-		config: cfg,
+func NewWatcherManager(logger logr.Logger, kubeClient *client.KubeClient, cfg *config.Config, startInterval time.Duration) *WatcherManager {
+	m := &WatcherManager{
+		logger:        logger,
+		kube:          kubeClient,
+		config:        cfg,
+		startInterval: startInterval,
+		watching:      make(map[schema.GroupVersionKind]bool),
+		forbidden:     make(map[schema.GroupVersionKind]string),
 	}
+	m.collector = watch.NewCollector(func() []*watch.Watcher {
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+		return m.watchers
+	})
+	return m
 }
 
 func (m *WatcherManager) Start(ctx context.Context) error {
+	m.restoreSnapshots()
 	resources, err := m.targetResources()
 	if err != nil {
 		return err
 	}
+	if err := m.startWatchers(ctx, resources); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.initialized = true
+	m.mu.Unlock()
+	if m.config.Persistence != nil {
+		go m.persist(ctx)
+	}
+
+	ticker := time.NewTicker(discoveryRetryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			klog.V(3).Info("done")
+			return nil
+		case <-ticker.C:
+			if len(m.FailedGroups()) == 0 && len(m.Forbidden()) == 0 {
+				continue
+			}
+			resources, err := m.targetResources()
+			if err != nil {
+				m.logger.Error(err, "failed to retry discovery")
+				continue
+			}
+			// A watcher failing to start must not stop the watchers already running
+			if err := m.startWatchers(ctx, resources); err != nil {
+				m.logger.Error(err, "failed to start watchers on retry")
+				continue
+			}
+		}
+	}
+}
 
+// SetEventSink sets the sink to which the events counted by the watchers are sent. It has to be called before Start.
+func (m *WatcherManager) SetEventSink(sink watch.EventSink) {
+	m.sink = sink
+}
+
+// startWatchers starts watchers for the resources that are not watched yet.
+func (m *WatcherManager) startWatchers(ctx context.Context, resources []schema.GroupVersionKind) error {
+	started := 0
 	for _, res := range resources {
+		m.mu.RLock()
+		watching := m.watching[res]
+		m.mu.RUnlock()
+		if watching {
+			continue
+		}
+
+		if started > 0 && m.startInterval > 0 {
+			// Stagger the start of informers not to flood kube-apiserver with list requests
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(m.startInterval):
+			}
+		}
+		if !m.canWatch(ctx, res) {
+			continue
+		}
 		klog.V(2).Info("create watcher", res)
 		nsSelector, resSelector, err := m.config.SelectorFor(metav1.GroupVersionKind{
 			Group:   res.Group,
@@ -44,22 +135,108 @@ func (m *WatcherManager) Start(ctx context.Context) error {
 		if err != nil {
 			return err
 		}
-		watcher := watch.NewWatcher(m.logger, m.kube, res, nsSelector, resSelector)
-		m.watchers = append(m.watchers, watcher)
+		watcher := watch.NewWatcher(m.logger, m.kube, res, nsSelector, resSelector, false)
+		m.restoreWatcher(watcher)
+		if m.sink != nil {
+			watcher.SetEventSink(m.sink)
+		}
 		klog.V(2).Info("start watcher", res)
 		err = watcher.Start(ctx)
 		if err != nil {
 			return err
 		}
-	}
+		started++
 
-	select {
-	case <-ctx.Done():
-		klog.V(3).Info("done")
+		m.mu.Lock()
+		m.watchers = append(m.watchers, watcher)
+		m.watching[res] = true
+		m.mu.Unlock()
 	}
 	return nil
 }
 
+// ReadyChecker returns a healthz.Checker that succeeds when the informers of all watchers have synced.
+// Replicas not elected as the leader do not watch resources, so they are always ready.
+func (m *WatcherManager) ReadyChecker(elected <-chan struct{}) healthz.Checker {
+	return func(_ *http.Request) error {
+		select {
+		case <-elected:
+		default:
+			return nil
+		}
+
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+		if !m.initialized {
+			return errors.New("watchers are not started yet")
+		}
+		unsynced := make([]string, 0)
+		for _, watcher := range m.watchers {
+			if !watcher.HasSynced() {
+				unsynced = append(unsynced, watcher.GroupVersionKind().String())
+			}
+		}
+		if len(unsynced) > 0 {
+			return fmt.Errorf("informers are not synced: %s", strings.Join(unsynced, ", "))
+		}
+		return nil
+	}
+}
+
+// canWatch checks the permissions to watch the resource, and records the reason if it is forbidden.
+// The forbidden resources are checked again later, because the permissions may be granted afterwards.
+func (m *WatcherManager) canWatch(ctx context.Context, gvk schema.GroupVersionKind) bool {
+	allowed, reason, err := m.kube.CanWatch(ctx, gvk)
+	if err != nil {
+		m.logger.Error(err, "failed to check permissions", "gvk", gvk.String())
+		reason = "failed to check permissions: " + err.Error()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if allowed {
+		delete(m.forbidden, gvk)
+		return true
+	}
+	if _, ok := m.forbidden[gvk]; !ok {
+		m.logger.Info("skip watching forbidden resource", "gvk", gvk.String(), "reason", reason)
+	}
+	m.forbidden[gvk] = reason
+	return false
+}
+
+// Forbidden returns the resources that are not watched due to lack of permissions, with the reasons.
+func (m *WatcherManager) Forbidden() map[schema.GroupVersionKind]string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make(map[schema.GroupVersionKind]string, len(m.forbidden))
+	for gvk, reason := range m.forbidden {
+		result[gvk] = reason
+	}
+	return result
+}
+
+// Watchers returns the running watchers.
+func (m *WatcherManager) Watchers() []*watch.Watcher {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.watchers
+}
+
+// FailedGroups returns the API groups that failed to be discovered.
+func (m *WatcherManager) FailedGroups() map[schema.GroupVersion]error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make(map[schema.GroupVersion]error, len(m.failedGroups))
+	for gv, err := range m.failedGroups {
+		result[gv] = err
+	}
+	return result
+}
+
 func (m *WatcherManager) targetResources() ([]schema.GroupVersionKind, error) {
 	targets := make([]schema.GroupVersionKind, 0)
 
@@ -77,26 +254,22 @@ func (m *WatcherManager) targetResources() ([]schema.GroupVersionKind, error) {
 			targets = append(targets, gvk)
 		}
 	} else {
-		serverResources, err := m.kube.Discovery.ServerPreferredResources()
+		resources, failedGroups, err := m.kube.DiscoverResources(!m.config.EnableClusterResources)
 		if err != nil {
 			return nil, err
 		}
-		for _, resList := range serverResources {
-			for _, res := range resList.APIResources {
-				if !m.config.EnableClusterResources && !res.Namespaced {
-					continue
-				}
-				gv, err := schema.ParseGroupVersion(resList.GroupVersion)
-				if err != nil {
-					gv = schema.GroupVersion{}
-				}
-				gvk := gv.WithKind(res.Kind)
-				if client.IsExcludedResource(gvk) {
-					continue
-				}
-				targets = append(targets, gvk)
+		for gv, err := range failedGroups {
+			m.logger.Error(err, "failed to discover API group, it will be retried later", "groupVersion", gv.String())
+		}
+		m.mu.Lock()
+		for gv := range m.failedGroups {
+			if _, ok := failedGroups[gv]; !ok {
+				m.logger.Info("API group is discovered", "groupVersion", gv.String())
 			}
 		}
+		m.failedGroups = failedGroups
+		m.mu.Unlock()
+		targets = append(targets, resources...)
 	}
 
 	return targets, nil
@@ -0,0 +1,264 @@
+package audit
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/zoetrope/kubbernecker/pkg/watch"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+)
+
+// clusterScopedKinds are the built-in kinds that are not namespaced. The scheme does not know the scopes of the kinds.
+var clusterScopedKinds = map[schema.GroupKind]bool{
+	{Group: "", Kind: "Namespace"}:                                                    true,
+	{Group: "", Kind: "Node"}:                                                         true,
+	{Group: "", Kind: "PersistentVolume"}:                                             true,
+	{Group: "", Kind: "ComponentStatus"}:                                              true,
+	{Group: "admissionregistration.k8s.io", Kind: "MutatingWebhookConfiguration"}:     true,
+	{Group: "admissionregistration.k8s.io", Kind: "ValidatingWebhookConfiguration"}:   true,
+	{Group: "admissionregistration.k8s.io", Kind: "ValidatingAdmissionPolicy"}:        true,
+	{Group: "admissionregistration.k8s.io", Kind: "ValidatingAdmissionPolicyBinding"}: true,
+	{Group: "apiserverinternal.k8s.io", Kind: "StorageVersion"}:                       true,
+	{Group: "authentication.k8s.io", Kind: "TokenReview"}:                             true,
+	{Group: "authentication.k8s.io", Kind: "SelfSubjectReview"}:                       true,
+	{Group: "authorization.k8s.io", Kind: "SubjectAccessReview"}:                      true,
+	{Group: "authorization.k8s.io", Kind: "SelfSubjectAccessReview"}:                  true,
+	{Group: "authorization.k8s.io", Kind: "SelfSubjectRulesReview"}:                   true,
+	{Group: "certificates.k8s.io", Kind: "CertificateSigningRequest"}:                 true,
+	{Group: "flowcontrol.apiserver.k8s.io", Kind: "FlowSchema"}:                       true,
+	{Group: "flowcontrol.apiserver.k8s.io", Kind: "PriorityLevelConfiguration"}:       true,
+	{Group: "networking.k8s.io", Kind: "IngressClass"}:                                true,
+	{Group: "networking.k8s.io", Kind: "ClusterCIDR"}:                                 true,
+	{Group: "node.k8s.io", Kind: "RuntimeClass"}:                                      true,
+	{Group: "policy", Kind: "PodSecurityPolicy"}:                                      true,
+	{Group: "rbac.authorization.k8s.io", Kind: "ClusterRole"}:                         true,
+	{Group: "rbac.authorization.k8s.io", Kind: "ClusterRoleBinding"}:                  true,
+	{Group: "resource.k8s.io", Kind: "ResourceClass"}:                                 true,
+	{Group: "scheduling.k8s.io", Kind: "PriorityClass"}:                               true,
+	{Group: "storage.k8s.io", Kind: "CSIDriver"}:                                      true,
+	{Group: "storage.k8s.io", Kind: "CSINode"}:                                        true,
+	{Group: "storage.k8s.io", Kind: "StorageClass"}:                                   true,
+	{Group: "storage.k8s.io", Kind: "VolumeAttachment"}:                               true,
+}
+
+// DefaultRESTMapper returns a RESTMapper for the built-in resources, which can be used without a cluster.
+func DefaultRESTMapper() meta.RESTMapper {
+	mapper := meta.NewDefaultRESTMapper(clientgoscheme.Scheme.PrioritizedVersionsAllGroups())
+	for gvk := range clientgoscheme.Scheme.AllKnownTypes() {
+		if gvk.Version == runtime.APIVersionInternal || strings.HasSuffix(gvk.Kind, "List") {
+			continue
+		}
+		scope := meta.RESTScopeNamespace
+		if clusterScopedKinds[gvk.GroupKind()] {
+			scope = meta.RESTScopeRoot
+		}
+		mapper.Add(gvk, scope)
+	}
+	return mapper
+}
+
+// ResolveResource converts TYPE[.VERSION][.GROUP] argument into a GroupResource.
+func ResolveResource(mapper meta.RESTMapper, arg string) schema.GroupResource {
+	gvr, gr := schema.ParseResourceArg(arg)
+	if gvr != nil {
+		if res, err := mapper.ResourceFor(*gvr); err == nil {
+			return res.GroupResource()
+		}
+	}
+	if res, err := mapper.ResourceFor(gr.WithVersion("")); err == nil {
+		return res.GroupResource()
+	}
+	return gr
+}
+
+// KindFor returns the kind of the resource. Audit events only contain the resource name,
+// so the resource name is used as the kind for unknown resources such as custom resources.
+func KindFor(mapper meta.RESTMapper, gvr schema.GroupVersionResource) metav1.GroupVersionKind {
+	gvk, err := mapper.KindFor(gvr)
+	if err != nil {
+		gvk = gvr.GroupVersion().WithKind(gvr.Resource)
+	}
+	return metav1.GroupVersionKind{
+		Group:   gvk.Group,
+		Version: gvk.Version,
+		Kind:    gvk.Kind,
+	}
+}
+
+// Analyzer aggregates write requests recorded in audit events into watch.Statistics.
+type Analyzer struct {
+	mapper     meta.RESTMapper
+	namespace  string
+	resources  []schema.GroupResource
+	statistics map[metav1.GroupVersionKind]*watch.Statistics
+}
+
+// NewAnalyzer creates an Analyzer.
+// If namespace is empty, resources in all namespaces are counted. If resources is empty, all resources are counted.
+func NewAnalyzer(mapper meta.RESTMapper, namespace string, resources []schema.GroupResource) *Analyzer {
+	return &Analyzer{
+		mapper:     mapper,
+		namespace:  namespace,
+		resources:  resources,
+		statistics: make(map[metav1.GroupVersionKind]*watch.Statistics),
+	}
+}
+
+func (a *Analyzer) isTarget(w *Write) bool {
+	if a.namespace != "" && w.Namespace != a.namespace {
+		return false
+	}
+	if len(a.resources) == 0 {
+		return true
+	}
+	for _, res := range a.resources {
+		if res == w.Resource.GroupResource() {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *Analyzer) Add(w *Write) {
+	if !a.isTarget(w) {
+		return
+	}
+
+	gvk := KindFor(a.mapper, w.Resource)
+	if _, ok := a.statistics[gvk]; !ok {
+		a.statistics[gvk] = &watch.Statistics{
+			GroupVersionKind: gvk,
+			Namespaces:       make(map[string]*watch.NamespaceStatistics),
+		}
+	}
+	statistics := a.statistics[gvk]
+
+	if _, ok := statistics.Namespaces[w.Namespace]; !ok {
+		statistics.Namespaces[w.Namespace] = &watch.NamespaceStatistics{
+			Resources: make(map[string]*watch.ResourceStatistics),
+		}
+	}
+	info := statistics.Namespaces[w.Namespace]
+
+	if _, ok := info.Resources[w.Name]; !ok {
+		info.Resources[w.Name] = &watch.ResourceStatistics{
+			Users:      make(map[string]*watch.ResourceStatistics),
+			UserAgents: make(map[string]*watch.ResourceStatistics),
+		}
+	}
+	resInfo := info.Resources[w.Name]
+	if _, ok := resInfo.Users[w.Username]; !ok {
+		resInfo.Users[w.Username] = &watch.ResourceStatistics{}
+	}
+	if _, ok := resInfo.UserAgents[w.UserAgent]; !ok {
+		resInfo.UserAgents[w.UserAgent] = &watch.ResourceStatistics{}
+	}
+
+	for _, s := range []*watch.ResourceStatistics{resInfo, resInfo.Users[w.Username], resInfo.UserAgents[w.UserAgent]} {
+		switch w.EventType {
+		case EventAdd:
+			s.AddCount += 1
+		case EventUpdate:
+			s.UpdateCount += 1
+		case EventDelete:
+			s.DeleteCount += 1
+		}
+	}
+}
+
+// Statistics returns the statistics for each GroupVersionKind, sorted by GroupVersionKind.
+func (a *Analyzer) Statistics() []*watch.Statistics {
+	result := make([]*watch.Statistics, 0, len(a.statistics))
+	for _, statistics := range a.statistics {
+		result = append(result, statistics.DeepCopy())
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].GroupVersionKind.String() < result[j].GroupVersionKind.String()
+	})
+	return result
+}
+
+// BlameAnalyzer aggregates write requests to a resource recorded in audit events into watch.BlameStatistics.
+type BlameAnalyzer struct {
+	resource   schema.GroupResource
+	namespace  string
+	name       string
+	statistics watch.BlameStatistics
+}
+
+// NewBlameAnalyzer creates a BlameAnalyzer. If namespace is empty, resources with the name in any namespace are counted.
+func NewBlameAnalyzer(resource schema.GroupResource, namespace, name string) *BlameAnalyzer {
+	statistics := watch.BlameStatistics{}
+	statistics.Managers = make(map[string]*watch.ManagerStatistics)
+
+	return &BlameAnalyzer{
+		resource:   resource,
+		namespace:  namespace,
+		name:       name,
+		statistics: statistics,
+	}
+}
+
+func (a *BlameAnalyzer) Add(w *Write) {
+	if w.Resource.GroupResource() != a.resource || w.Name != a.name {
+		return
+	}
+	if a.namespace != "" && w.Namespace != a.namespace {
+		return
+	}
+
+	switch w.EventType {
+	case EventAdd:
+		a.recreate(w)
+		created := w.Time
+		a.statistics.CreatedAt = &created
+		a.statistics.CreatedBy = w.Manager
+		a.statistics.LatestUpdate = created
+	case EventUpdate:
+		if w.UID != "" && w.UID != a.statistics.UID {
+			a.recreate(w)
+		}
+		if _, ok := a.statistics.Managers[w.Manager]; !ok {
+			a.statistics.Managers[w.Manager] = &watch.ManagerStatistics{
+				Users:      make(map[string]*watch.ManagerStatistics),
+				UserAgents: make(map[string]*watch.ManagerStatistics),
+			}
+		}
+		manager := a.statistics.Managers[w.Manager]
+		if _, ok := manager.Users[w.Username]; !ok {
+			manager.Users[w.Username] = &watch.ManagerStatistics{}
+		}
+		if _, ok := manager.UserAgents[w.UserAgent]; !ok {
+			manager.UserAgents[w.UserAgent] = &watch.ManagerStatistics{}
+		}
+		manager.UpdateCount += 1
+		manager.Users[w.Username].UpdateCount += 1
+		manager.UserAgents[w.UserAgent].UpdateCount += 1
+		if w.Time.After(a.statistics.LatestUpdate) {
+			a.statistics.LatestUpdate = w.Time
+		}
+	case EventDelete:
+		deleted := w.Time
+		a.statistics.DeletedAt = &deleted
+	}
+}
+
+// recreate starts counting a new incarnation of the resource and keeps the previous one in the history.
+func (a *BlameAnalyzer) recreate(w *Write) {
+	current := a.statistics.IncarnationStatistics
+	if current.UID != "" || current.CreatedAt != nil || current.DeletedAt != nil || len(current.Managers) > 0 {
+		a.statistics.History = append(a.statistics.History, current)
+	}
+	a.statistics.IncarnationStatistics = watch.IncarnationStatistics{
+		UID:      w.UID,
+		Managers: make(map[string]*watch.ManagerStatistics),
+	}
+}
+
+func (a *BlameAnalyzer) Statistics() *watch.BlameStatistics {
+	return a.statistics.DeepCopy()
+}
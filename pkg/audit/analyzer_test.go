@@ -0,0 +1,146 @@
+package audit
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+	"github.com/zoetrope/kubbernecker/pkg/watch"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	auditv1 "k8s.io/apiserver/pkg/apis/audit/v1"
+)
+
+var _ = Describe("Test Analyzer", func() {
+	var writes []*Write
+
+	BeforeEach(func() {
+		f, err := os.Open("testdata/audit.log")
+		Expect(err).ShouldNot(HaveOccurred())
+		defer f.Close()
+
+		writes = nil
+		err = Decode(f, func(ev *auditv1.Event) error {
+			if w, ok := ParseWrite(ev); ok {
+				writes = append(writes, w)
+			}
+			return nil
+		})
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	It("should parse only completed and successful write requests", func() {
+		Expect(writes).Should(HaveLen(5))
+		Expect(writes[0]).Should(PointTo(MatchFields(IgnoreExtras, Fields{
+			"Resource":  Equal(schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}),
+			"Namespace": Equal("default"),
+			"Name":      Equal("test"),
+			"EventType": Equal(EventUpdate),
+			"Manager":   Equal("kubectl"),
+			"Username":  Equal("alice"),
+			"UserAgent": Equal("kubectl/v1.26.0"),
+		})))
+		Expect(writes[1].Manager).Should(Equal("my-operator"))
+		Expect(writes[2]).Should(PointTo(MatchFields(IgnoreExtras, Fields{
+			"Name":      Equal("nginx"),
+			"UID":       Equal(types.UID("u1")),
+			"EventType": Equal(EventAdd),
+		})))
+	})
+
+	It("should map the built-in resources with their scopes", func() {
+		mapper := DefaultRESTMapper()
+		for kind, scope := range map[schema.GroupKind]meta.RESTScopeName{
+			{Kind: "ConfigMap"}:                                       meta.RESTScopeNameNamespace,
+			{Kind: "Namespace"}:                                       meta.RESTScopeNameRoot,
+			{Kind: "Node"}:                                            meta.RESTScopeNameRoot,
+			{Kind: "PersistentVolume"}:                                meta.RESTScopeNameRoot,
+			{Group: "apps", Kind: "Deployment"}:                       meta.RESTScopeNameNamespace,
+			{Group: "rbac.authorization.k8s.io", Kind: "Role"}:        meta.RESTScopeNameNamespace,
+			{Group: "rbac.authorization.k8s.io", Kind: "ClusterRole"}: meta.RESTScopeNameRoot,
+			{Group: "storage.k8s.io", Kind: "StorageClass"}:           meta.RESTScopeNameRoot,
+		} {
+			mapping, err := mapper.RESTMapping(kind)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(mapping.Scope.Name()).Should(Equal(scope), kind.String())
+		}
+	})
+
+	It("should count the changes for each user and user agent", func() {
+		mapper := DefaultRESTMapper()
+		analyzer := NewAnalyzer(mapper, "default", []schema.GroupResource{ResolveResource(mapper, "configmap")})
+		for _, w := range writes {
+			analyzer.Add(w)
+		}
+
+		statistics := analyzer.Statistics()
+		Expect(statistics).Should(HaveLen(1))
+		Expect(statistics[0].GroupVersionKind).Should(Equal(metav1.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}))
+		Expect(statistics[0].Namespaces["default"].Resources["test"]).Should(PointTo(MatchAllFields(Fields{
+			"AddCount":       Equal(1),
+			"UpdateCount":    Equal(2),
+			"DeleteCount":    Equal(1),
+			"MinInterval":    BeZero(),
+			"MedianInterval": BeZero(),
+			"Users": MatchAllKeys(Keys{
+				"alice":                            Equal(&watch.ResourceStatistics{UpdateCount: 1}),
+				"system:serviceaccount:default:op": Equal(&watch.ResourceStatistics{UpdateCount: 1}),
+				"helm":                             Equal(&watch.ResourceStatistics{AddCount: 1, DeleteCount: 1}),
+			}),
+			"UserAgents": MatchAllKeys(Keys{
+				"kubectl/v1.26.0": Equal(&watch.ResourceStatistics{UpdateCount: 1}),
+				"manager/v0.0.0":  Equal(&watch.ResourceStatistics{UpdateCount: 1}),
+				"Helm/3.11.1":     Equal(&watch.ResourceStatistics{AddCount: 1, DeleteCount: 1}),
+			}),
+		})))
+	})
+
+	It("should count all resources if no resource is specified", func() {
+		analyzer := NewAnalyzer(DefaultRESTMapper(), "", nil)
+		for _, w := range writes {
+			analyzer.Add(w)
+		}
+
+		statistics := analyzer.Statistics()
+		Expect(statistics).Should(HaveLen(2))
+		Expect(statistics[0].GroupVersionKind.Kind).Should(Equal("ConfigMap"))
+		Expect(statistics[1].GroupVersionKind.Kind).Should(Equal("Deployment"))
+	})
+
+	It("should print managers and users that updated the resource", func() {
+		analyzer := NewBlameAnalyzer(ResolveResource(DefaultRESTMapper(), "configmap"), "default", "test")
+		for _, w := range writes {
+			analyzer.Add(w)
+		}
+
+		statistics := analyzer.Statistics()
+		Expect(statistics.UID).Should(Equal(types.UID("u2")))
+		Expect(statistics.CreatedBy).Should(Equal("Helm"))
+		Expect(statistics.Managers).Should(BeEmpty())
+		Expect(statistics.History).Should(HaveLen(1))
+		Expect(statistics.History[0].DeletedAt).ShouldNot(BeNil())
+		Expect(statistics.History[0].Managers).Should(MatchAllKeys(Keys{
+			"kubectl": PointTo(MatchAllFields(Fields{
+				"UpdateCount": Equal(1),
+				"Users": MatchAllKeys(Keys{
+					"alice": Equal(&watch.ManagerStatistics{UpdateCount: 1}),
+				}),
+				"UserAgents": MatchAllKeys(Keys{
+					"kubectl/v1.26.0": Equal(&watch.ManagerStatistics{UpdateCount: 1}),
+				}),
+			})),
+			"my-operator": PointTo(MatchAllFields(Fields{
+				"UpdateCount": Equal(1),
+				"Users": MatchAllKeys(Keys{
+					"system:serviceaccount:default:op": Equal(&watch.ManagerStatistics{UpdateCount: 1}),
+				}),
+				"UserAgents": MatchAllKeys(Keys{
+					"manager/v0.0.0": Equal(&watch.ManagerStatistics{UpdateCount: 1}),
+				}),
+			})),
+		}))
+	})
+})
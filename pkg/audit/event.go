@@ -0,0 +1,146 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	auditv1 "k8s.io/apiserver/pkg/apis/audit/v1"
+)
+
+const (
+	EventAdd    = "add"
+	EventUpdate = "update"
+	EventDelete = "delete"
+)
+
+// Write represents a write request to a Kubernetes resource recorded in an audit event.
+type Write struct {
+	Resource  schema.GroupVersionResource
+	Namespace string
+	Name      string
+	UID       types.UID
+	Verb      string
+	EventType string
+	Manager   string
+	Username  string
+	UserAgent string
+	Time      time.Time
+}
+
+// ParseWrite extracts the write request from the audit event.
+// It returns false if the event is not a completed and successful write request to a resource.
+func ParseWrite(ev *auditv1.Event) (*Write, bool) {
+	if ev.Stage != auditv1.StageResponseComplete || ev.ObjectRef == nil {
+		return nil, false
+	}
+	if ev.ResponseStatus != nil && (ev.ResponseStatus.Code < 200 || ev.ResponseStatus.Code >= 300) {
+		return nil, false
+	}
+	// Requests to other subresources (e.g. pods/exec, pods/eviction) do not write the resource itself
+	if ev.ObjectRef.Subresource != "" && ev.ObjectRef.Subresource != "status" {
+		return nil, false
+	}
+
+	var eventType string
+	switch ev.Verb {
+	case "create":
+		eventType = EventAdd
+	case "update", "patch":
+		eventType = EventUpdate
+	case "delete":
+		eventType = EventDelete
+	default:
+		return nil, false
+	}
+
+	w := &Write{
+		Resource: schema.GroupVersionResource{
+			Group:    ev.ObjectRef.APIGroup,
+			Version:  ev.ObjectRef.APIVersion,
+			Resource: ev.ObjectRef.Resource,
+		},
+		Namespace: ev.ObjectRef.Namespace,
+		Name:      ev.ObjectRef.Name,
+		UID:       ev.ObjectRef.UID,
+		Verb:      ev.Verb,
+		EventType: eventType,
+		Manager:   fieldManager(ev),
+		Username:  ev.User.Username,
+		UserAgent: ev.UserAgent,
+		Time:      ev.StageTimestamp.Time,
+	}
+
+	// The name of created resources is only available in the request or response object
+	for _, obj := range []*metav1.PartialObjectMetadata{objectMeta(ev.ResponseObject), objectMeta(ev.RequestObject)} {
+		if obj == nil {
+			continue
+		}
+		if w.Name == "" {
+			w.Name = obj.Name
+		}
+		if w.UID == "" {
+			w.UID = obj.UID
+		}
+	}
+	if w.Name == "" {
+		return nil, false
+	}
+	return w, true
+}
+
+// fieldManager returns the manager name recorded in managedFields for the request.
+// If the request does not specify it, kube-apiserver uses the prefix of the user agent.
+func fieldManager(ev *auditv1.Event) string {
+	if u, err := url.ParseRequestURI(ev.RequestURI); err == nil {
+		if manager := u.Query().Get("fieldManager"); manager != "" {
+			return manager
+		}
+	}
+	manager := strings.Split(ev.UserAgent, "/")[0]
+	if len(manager) > 128 {
+		manager = manager[:128]
+	}
+	return manager
+}
+
+func objectMeta(obj *runtime.Unknown) *metav1.PartialObjectMetadata {
+	if obj == nil || len(obj.Raw) == 0 {
+		return nil
+	}
+	meta := &metav1.PartialObjectMetadata{}
+	if err := json.Unmarshal(obj.Raw, meta); err != nil {
+		return nil
+	}
+	return meta
+}
+
+// Decode reads audit events written by the log backend of kube-apiserver, one JSON object per line.
+func Decode(r io.Reader, fn func(ev *auditv1.Event) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := scanner.Bytes()
+		if len(strings.TrimSpace(string(data))) == 0 {
+			continue
+		}
+		ev := &auditv1.Event{}
+		if err := json.Unmarshal(data, ev); err != nil {
+			return fmt.Errorf("failed to decode audit event at line %d: %w", line, err)
+		}
+		if err := fn(ev); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,160 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/zoetrope/kubbernecker/pkg/watch"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	auditv1 "k8s.io/apiserver/pkg/apis/audit/v1"
+)
+
+// Source is a watch.EventSource delivering the write requests to a resource type recorded in audit events.
+// Audit events do not contain the metadata of the objects, so it is synthesized from the requests:
+// managedFields have an entry for the field manager of each request, so that the writes are attributed to it.
+type Source struct {
+	r      io.Reader
+	mapper meta.RESTMapper
+	gk     schema.GroupKind
+
+	objects         map[string]*metav1.PartialObjectMetadata
+	resourceVersion int
+
+	synced atomic.Bool
+	err    error
+}
+
+// NewSource creates a Source. The requests to the resources of the kind in any version are delivered.
+func NewSource(r io.Reader, mapper meta.RESTMapper, gvk schema.GroupVersionKind) *Source {
+	return &Source{
+		r:       r,
+		mapper:  mapper,
+		gk:      gvk.GroupKind(),
+		objects: make(map[string]*metav1.PartialObjectMetadata),
+	}
+}
+
+// Start reads the audit events in the background. HasSynced returns true when all the events have been delivered.
+func (s *Source) Start(ctx context.Context, handler watch.EventHandler) error {
+	go func() {
+		s.err = s.Run(ctx, handler)
+		s.synced.Store(true)
+	}()
+	return nil
+}
+
+// Run reads the audit events until the end of the stream.
+func (s *Source) Run(ctx context.Context, handler watch.EventHandler) error {
+	return Decode(s.r, func(ev *auditv1.Event) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		w, ok := ParseWrite(ev)
+		if !ok {
+			return nil
+		}
+		gvk := KindFor(s.mapper, w.Resource)
+		if gvk.Group != s.gk.Group || gvk.Kind != s.gk.Kind {
+			return nil
+		}
+		for _, event := range s.sourceEvents(w) {
+			handler.OnEvent(event)
+		}
+		return nil
+	})
+}
+
+func (s *Source) HasSynced() bool {
+	return s.synced.Load()
+}
+
+// Err returns the error occurred while reading the audit events in the background. It is only valid after HasSynced returns true.
+func (s *Source) Err() error {
+	if !s.synced.Load() {
+		return nil
+	}
+	return s.err
+}
+
+// GetObject returns the metadata synthesized from the requests to the resource.
+func (s *Source) GetObject(namespace, name string) (*metav1.PartialObjectMetadata, bool) {
+	if !s.synced.Load() {
+		return nil, false
+	}
+	obj, ok := s.objects[namespace+"/"+name]
+	return obj, ok
+}
+
+// sourceEvents synthesizes the metadata of the object after the write.
+// If the object written first is not created in the audit events, it is delivered as an object in the initial list beforehand.
+func (s *Source) sourceEvents(w *Write) []*watch.SourceEvent {
+	var events []*watch.SourceEvent
+	key := w.Namespace + "/" + w.Name
+	oldObj := s.objects[key]
+	if oldObj == nil && w.EventType != EventAdd {
+		oldObj = s.newObject(w)
+		s.objects[key] = oldObj
+		events = append(events, &watch.SourceEvent{Type: EventAdd, Object: oldObj, Initial: true, Time: w.Time})
+	}
+
+	var obj *metav1.PartialObjectMetadata
+	if w.EventType == EventAdd {
+		obj = s.newObject(w)
+		obj.CreationTimestamp = metav1.NewTime(w.Time)
+	} else {
+		obj = oldObj.DeepCopy()
+		s.resourceVersion++
+		obj.ResourceVersion = strconv.Itoa(s.resourceVersion)
+	}
+	if w.EventType != EventDelete {
+		obj.ManagedFields = updateManagedFields(obj.ManagedFields, w.Manager, metav1.NewTime(w.Time))
+	}
+
+	event := &watch.SourceEvent{Type: w.EventType, Object: obj, Time: w.Time}
+	if w.EventType == EventUpdate {
+		event.OldObject = oldObj
+	}
+	if w.EventType == EventDelete {
+		delete(s.objects, key)
+	} else {
+		s.objects[key] = obj
+	}
+	return append(events, event)
+}
+
+func (s *Source) newObject(w *Write) *metav1.PartialObjectMetadata {
+	s.resourceVersion++
+	obj := &metav1.PartialObjectMetadata{}
+	obj.Namespace = w.Namespace
+	obj.Name = w.Name
+	obj.UID = w.UID
+	if obj.UID == "" {
+		// A new UID is given to each incarnation of the resource
+		obj.UID = types.UID(fmt.Sprintf("audit-%d", s.resourceVersion))
+	}
+	obj.ResourceVersion = strconv.Itoa(s.resourceVersion)
+	return obj
+}
+
+// updateManagedFields sets the time of the entry of the manager, as kube-apiserver does for an update request.
+func updateManagedFields(fields []metav1.ManagedFieldsEntry, manager string, t metav1.Time) []metav1.ManagedFieldsEntry {
+	result := make([]metav1.ManagedFieldsEntry, 0, len(fields)+1)
+	found := false
+	for _, field := range fields {
+		if field.Manager == manager && field.Operation == metav1.ManagedFieldsOperationUpdate {
+			field.Time = &t
+			found = true
+		}
+		result = append(result, field)
+	}
+	if !found {
+		result = append(result, metav1.ManagedFieldsEntry{Manager: manager, Operation: metav1.ManagedFieldsOperationUpdate, Time: &t})
+	}
+	return result
+}
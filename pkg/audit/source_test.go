@@ -0,0 +1,68 @@
+package audit
+
+import (
+	"context"
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+	"github.com/zoetrope/kubbernecker/pkg/watch"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var _ = Describe("Test Source", func() {
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	var source *Source
+
+	BeforeEach(func() {
+		f, err := os.Open("testdata/audit.log")
+		Expect(err).ShouldNot(HaveOccurred())
+		DeferCleanup(f.Close)
+		source = NewSource(f, DefaultRESTMapper(), gvk)
+	})
+
+	It("should count the write requests through the Watcher", func() {
+		watcher := watch.NewWatcherWithSource(ctrl.Log.WithName("audit-test"), source, "", gvk, labels.Everything(), false)
+		Expect(watcher.Start(context.Background())).Should(Succeed())
+		Eventually(watcher.HasSynced).Should(BeTrue())
+		Expect(source.Err()).ShouldNot(HaveOccurred())
+
+		statistics := watcher.Statistics()
+		Expect(statistics.Namespaces).Should(MatchAllKeys(Keys{
+			"default": PointTo(MatchFields(IgnoreExtras, Fields{
+				"Resources": MatchAllKeys(Keys{
+					"test": PointTo(MatchFields(IgnoreExtras, Fields{
+						"AddCount":    Equal(1),
+						"UpdateCount": Equal(2),
+						"DeleteCount": Equal(1),
+					})),
+				}),
+			})),
+		}))
+		Expect(watcher.ManagerUpdates("default", "test")).Should(BeEmpty())
+		obj, ok := watcher.Object("default", "test")
+		Expect(ok).Should(BeTrue())
+		Expect(obj.ManagedFields).Should(ConsistOf(MatchFields(IgnoreExtras, Fields{"Manager": Equal("Helm")})))
+	})
+
+	It("should attribute the write requests to the field managers through the BlameWatcher", func() {
+		watcher := watch.NewBlameWatcherWithSource(ctrl.Log.WithName("audit-test"), nil, "", gvk, "default", "test")
+		Expect(source.Run(context.Background(), watcher)).Should(Succeed())
+
+		statistics := watcher.Statistics()
+		Expect(statistics.CreatedBy).Should(Equal("Helm"))
+		Expect(statistics.Managers).Should(BeEmpty())
+		Expect(statistics.History).Should(ConsistOf(MatchFields(IgnoreExtras, Fields{
+			"CreatedBy": BeEmpty(),
+			"DeletedAt": PointTo(BeTemporally("==", time.Date(2023, 2, 17, 13, 25, 25, 100000000, time.UTC))),
+			"Managers": MatchAllKeys(Keys{
+				"kubectl":     PointTo(MatchFields(IgnoreExtras, Fields{"UpdateCount": Equal(1)})),
+				"my-operator": PointTo(MatchFields(IgnoreExtras, Fields{"UpdateCount": Equal(1)})),
+			}),
+		})))
+	})
+})
@@ -1,17 +1,22 @@
 package client
 
 import (
+	"context"
 	"fmt"
+	"sort"
 	"time"
 
 	"k8s.io/utils/pointer"
 
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/client-go/discovery"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/cluster"
@@ -20,6 +25,13 @@ import (
 type KubeClient struct {
 	Cluster   cluster.Cluster
 	Discovery *discovery.DiscoveryClient
+
+	// Name is the name of the kubeconfig context. It is only set when watching multiple clusters.
+	Name string
+	// Namespace is the default namespace of the kubeconfig context.
+	Namespace string
+	// WatchNamespace is the namespace to which the cache is restricted. If this is empty, all namespaces are watched.
+	WatchNamespace string
 }
 
 func NewCachingClient(cache cache.Cache, config *rest.Config, options client.Options, uncachedObjects ...client.Object) (client.Client, error) {
@@ -69,8 +81,9 @@ func MakeKubeClientFromRestConfig(cfg *rest.Config, namespace string) (*KubeClie
 	}
 
 	return &KubeClient{
-		Cluster:   c,
-		Discovery: disco,
+		Cluster:        c,
+		Discovery:      disco,
+		WatchNamespace: namespace,
 	}, nil
 }
 
@@ -80,15 +93,73 @@ func MakeKubeClient(config *genericclioptions.ConfigFlags, allNamespaces bool) (
 		return nil, err
 	}
 
+	defaultNamespace, _, err := config.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return nil, err
+	}
 	namespace := ""
 	if !allNamespaces {
-		namespace, _, err = config.ToRawKubeConfigLoader().Namespace()
-		if err != nil {
-			return nil, err
-		}
+		namespace = defaultNamespace
+	}
+
+	kube, err := MakeKubeClientFromRestConfig(cfg, namespace)
+	if err != nil {
+		return nil, err
+	}
+	kube.Namespace = defaultNamespace
+	return kube, nil
+}
+
+// MakeKubeClientForContext creates a KubeClient for the given context of the kubeconfig.
+// Only the kubeconfig and namespace flags and WrapConfigFn are respected, other flags such as `--server` or `--token` are ignored.
+func MakeKubeClientForContext(config *genericclioptions.ConfigFlags, contextName string, allNamespaces bool) (*KubeClient, error) {
+	rawConfig, err := config.ToRawKubeConfigLoader().RawConfig()
+	if err != nil {
+		return nil, err
+	}
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: contextName}
+	if config.Namespace != nil {
+		overrides.Context.Namespace = *config.Namespace
 	}
+	clientConfig := clientcmd.NewNonInteractiveClientConfig(rawConfig, contextName, overrides, nil)
 
-	return MakeKubeClientFromRestConfig(cfg, namespace)
+	cfg, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("invalid context %s: %w", contextName, err)
+	}
+	if config.WrapConfigFn != nil {
+		cfg = config.WrapConfigFn(cfg)
+	}
+	defaultNamespace, _, err := clientConfig.Namespace()
+	if err != nil {
+		return nil, err
+	}
+	namespace := ""
+	if !allNamespaces {
+		namespace = defaultNamespace
+	}
+
+	kube, err := MakeKubeClientFromRestConfig(cfg, namespace)
+	if err != nil {
+		return nil, err
+	}
+	kube.Name = contextName
+	kube.Namespace = defaultNamespace
+	return kube, nil
+}
+
+// Contexts returns the names of all contexts in the kubeconfig.
+func Contexts(config *genericclioptions.ConfigFlags) ([]string, error) {
+	rawConfig, err := config.ToRawKubeConfigLoader().RawConfig()
+	if err != nil {
+		return nil, err
+	}
+	contexts := make([]string, 0, len(rawConfig.Contexts))
+	for name := range rawConfig.Contexts {
+		contexts = append(contexts, name)
+	}
+	sort.Strings(contexts)
+	return contexts, nil
 }
 
 var excludedResources = []schema.GroupVersionKind{
@@ -201,3 +272,83 @@ func (k *KubeClient) IsValidGVK(gvk *schema.GroupVersionKind) error {
 	}
 	return nil
 }
+
+func (k *KubeClient) IsNamespaced(gvk *schema.GroupVersionKind) (bool, error) {
+	mapping, err := k.Cluster.GetRESTMapper().RESTMapping(schema.GroupKind{Group: gvk.Group, Kind: gvk.Kind}, gvk.Version)
+	if err != nil {
+		return false, fmt.Errorf("invalid gvk %s: %w", gvk.String(), err)
+	}
+	return mapping.Scope.Name() == meta.RESTScopeNameNamespace, nil
+}
+
+// DiscoverResources returns the preferred resources served by kube-apiserver excluding the excluded resources.
+// If some API groups cannot be discovered (e.g. an aggregated API server is unavailable),
+// the resources of the other groups are returned with the errors of the failed groups.
+func (k *KubeClient) DiscoverResources(namespacedOnly bool) ([]schema.GroupVersionKind, map[schema.GroupVersion]error, error) {
+	var failedGroups map[schema.GroupVersion]error
+	serverResources, err := k.Discovery.ServerPreferredResources()
+	if err != nil {
+		groupErr, ok := err.(*discovery.ErrGroupDiscoveryFailed)
+		if !ok {
+			return nil, nil, err
+		}
+		failedGroups = groupErr.Groups
+	}
+
+	targets := make([]schema.GroupVersionKind, 0)
+	for _, resList := range serverResources {
+		for _, res := range resList.APIResources {
+			if namespacedOnly && !res.Namespaced {
+				continue
+			}
+			gv, err := schema.ParseGroupVersion(resList.GroupVersion)
+			if err != nil {
+				gv = schema.GroupVersion{}
+			}
+			gvk := gv.WithKind(res.Kind)
+			if IsExcludedResource(gvk) {
+				continue
+			}
+			targets = append(targets, gvk)
+		}
+	}
+	return targets, failedGroups, nil
+}
+
+// CanWatch checks whether the user is allowed to list and watch the resources with SelfSubjectAccessReview.
+// If not allowed, the reason is returned.
+func (k *KubeClient) CanWatch(ctx context.Context, gvk schema.GroupVersionKind) (bool, string, error) {
+	mapping, err := k.Cluster.GetRESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return false, "", fmt.Errorf("invalid gvk %s: %w", gvk.String(), err)
+	}
+	namespace := ""
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		namespace = k.WatchNamespace
+	}
+
+	for _, verb := range []string{"list", "watch"} {
+		review := &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Namespace: namespace,
+					Verb:      verb,
+					Group:     mapping.Resource.Group,
+					Version:   mapping.Resource.Version,
+					Resource:  mapping.Resource.Resource,
+				},
+			},
+		}
+		if err := k.Cluster.GetClient().Create(ctx, review); err != nil {
+			return false, "", err
+		}
+		if !review.Status.Allowed {
+			reason := fmt.Sprintf("%s is forbidden", verb)
+			if review.Status.Reason != "" {
+				reason += ": " + review.Status.Reason
+			}
+			return false, reason, nil
+		}
+	}
+	return true, "", nil
+}
@@ -1,10 +1,17 @@
 package client
 
 import (
+	"context"
+	"path/filepath"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	. "github.com/onsi/gomega/gstruct"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
 )
 
 var _ = Describe("Test KubeClient", func() {
@@ -57,4 +64,60 @@ var _ = Describe("Test KubeClient", func() {
 		})
 		Expect(err).ShouldNot(HaveOccurred())
 	})
+
+	It("should make KubeClient for each context", func() {
+		kubeconfig := clientcmdapi.NewConfig()
+		kubeconfig.Clusters["envtest"] = &clientcmdapi.Cluster{
+			Server:                   cfg.Host,
+			CertificateAuthorityData: cfg.CAData,
+		}
+		kubeconfig.AuthInfos["envtest"] = &clientcmdapi.AuthInfo{
+			ClientCertificateData: cfg.CertData,
+			ClientKeyData:         cfg.KeyData,
+		}
+		kubeconfig.Contexts["cluster-b"] = &clientcmdapi.Context{Cluster: "envtest", AuthInfo: "envtest", Namespace: "kube-system"}
+		kubeconfig.Contexts["cluster-a"] = &clientcmdapi.Context{Cluster: "envtest", AuthInfo: "envtest"}
+		kubeconfig.CurrentContext = "cluster-a"
+		path := filepath.Join(GinkgoT().TempDir(), "kubeconfig")
+		err := clientcmd.WriteToFile(*kubeconfig, path)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		flags := genericclioptions.NewConfigFlags(true)
+		flags.KubeConfig = &path
+
+		contexts, err := Contexts(flags)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(contexts).Should(Equal([]string{"cluster-a", "cluster-b"}))
+
+		kube, err := MakeKubeClientForContext(flags, "cluster-b", true)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(kube.Name).Should(Equal("cluster-b"))
+		Expect(kube.Namespace).Should(Equal("kube-system"))
+
+		namespace := "default"
+		flags.Namespace = &namespace
+		kube, err = MakeKubeClientForContext(flags, "cluster-b", true)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(kube.Namespace).Should(Equal("default"))
+
+		_, err = MakeKubeClientForContext(flags, "unknown", true)
+		Expect(err).Should(HaveOccurred())
+	})
+
+	It("should check permissions to watch resources", func() {
+		gvk := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+		allowed, _, err := kubeClient.CanWatch(context.Background(), gvk)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(allowed).Should(BeTrue())
+
+		user, err := testEnv.ControlPlane.AddUser(envtest.User{Name: "unprivileged"}, nil)
+		Expect(err).ShouldNot(HaveOccurred())
+		kube, err := MakeKubeClientFromRestConfig(user.Config(), "default")
+		Expect(err).ShouldNot(HaveOccurred())
+
+		allowed, reason, err := kube.CanWatch(context.Background(), gvk)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(allowed).Should(BeFalse())
+		Expect(reason).Should(HavePrefix("list is forbidden"))
+	})
 })
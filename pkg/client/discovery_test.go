@@ -0,0 +1,68 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+)
+
+var _ = Describe("Test DiscoverResources", func() {
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		responses := map[string]interface{}{
+			"/api": &metav1.APIVersions{Versions: []string{"v1"}},
+			"/apis": &metav1.APIGroupList{Groups: []metav1.APIGroup{
+				{
+					Name:             "metrics.k8s.io",
+					Versions:         []metav1.GroupVersionForDiscovery{{GroupVersion: "metrics.k8s.io/v1beta1", Version: "v1beta1"}},
+					PreferredVersion: metav1.GroupVersionForDiscovery{GroupVersion: "metrics.k8s.io/v1beta1", Version: "v1beta1"},
+				},
+			}},
+			"/api/v1": &metav1.APIResourceList{
+				GroupVersion: "v1",
+				APIResources: []metav1.APIResource{
+					{Name: "configmaps", Namespaced: true, Kind: "ConfigMap", Verbs: []string{"list", "watch"}},
+					{Name: "namespaces", Namespaced: false, Kind: "Namespace", Verbs: []string{"list", "watch"}},
+				},
+			},
+		}
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			res, ok := responses[req.URL.Path]
+			if !ok {
+				http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(res)
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should return the resources of available groups with the failed groups", func() {
+		kube := &KubeClient{Discovery: discovery.NewDiscoveryClientForConfigOrDie(&rest.Config{Host: server.URL})}
+
+		resources, failedGroups, err := kube.DiscoverResources(false)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(resources).Should(ConsistOf(
+			schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"},
+			schema.GroupVersionKind{Version: "v1", Kind: "Namespace"},
+		))
+		Expect(failedGroups).Should(HaveLen(1))
+		Expect(failedGroups).Should(HaveKey(schema.GroupVersion{Group: "metrics.k8s.io", Version: "v1beta1"}))
+
+		resources, _, err = kube.DiscoverResources(true)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(resources).Should(ConsistOf(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}))
+	})
+})
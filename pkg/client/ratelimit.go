@@ -0,0 +1,90 @@
+package client
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+var (
+	throttledRequestsDesc = prometheus.NewDesc(
+		"kubbernecker_client_throttled_requests_total",
+		"Total number of requests to kube-apiserver delayed by client-side rate limiting",
+		nil, nil)
+	throttledSecondsDesc = prometheus.NewDesc(
+		"kubbernecker_client_throttled_seconds_total",
+		"Total seconds spent waiting for client-side rate limiting",
+		nil, nil)
+)
+
+// RateLimiter is a token bucket rate limiter for the requests to kube-apiserver,
+// which records how many requests are delayed by client-side throttling.
+type RateLimiter struct {
+	flowcontrol.RateLimiter
+	qps   float32
+	burst int
+
+	throttledRequests atomic.Int64
+	throttledNanos    atomic.Int64
+}
+
+func NewRateLimiter(qps float32, burst int) *RateLimiter {
+	return &RateLimiter{
+		RateLimiter: flowcontrol.NewTokenBucketRateLimiter(qps, burst),
+		qps:         qps,
+		burst:       burst,
+	}
+}
+
+// Apply configures the rest.Config to use the rate limiter.
+// All clients created from the rest.Config share the rate limiter.
+func (l *RateLimiter) Apply(cfg *rest.Config) *rest.Config {
+	cfg.QPS = l.qps
+	cfg.Burst = l.burst
+	cfg.RateLimiter = l
+	return cfg
+}
+
+func (l *RateLimiter) Accept() {
+	if l.RateLimiter.TryAccept() {
+		return
+	}
+	start := time.Now()
+	l.RateLimiter.Accept()
+	l.record(time.Since(start))
+}
+
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if l.RateLimiter.TryAccept() {
+		return nil
+	}
+	start := time.Now()
+	err := l.RateLimiter.Wait(ctx)
+	l.record(time.Since(start))
+	return err
+}
+
+func (l *RateLimiter) record(d time.Duration) {
+	l.throttledRequests.Add(1)
+	l.throttledNanos.Add(int64(d))
+}
+
+// Throttled returns the number of delayed requests and the total time spent waiting.
+func (l *RateLimiter) Throttled() (int64, time.Duration) {
+	return l.throttledRequests.Load(), time.Duration(l.throttledNanos.Load())
+}
+
+func (l *RateLimiter) Describe(ch chan<- *prometheus.Desc) {
+	ch <- throttledRequestsDesc
+	ch <- throttledSecondsDesc
+}
+
+func (l *RateLimiter) Collect(ch chan<- prometheus.Metric) {
+	requests, duration := l.Throttled()
+	ch <- prometheus.MustNewConstMetric(throttledRequestsDesc, prometheus.CounterValue, float64(requests))
+	ch <- prometheus.MustNewConstMetric(throttledSecondsDesc, prometheus.CounterValue, duration.Seconds())
+}
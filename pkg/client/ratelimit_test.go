@@ -0,0 +1,33 @@
+package client
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/client-go/rest"
+)
+
+var _ = Describe("Test RateLimiter", func() {
+	It("should count throttled requests", func() {
+		limiter := NewRateLimiter(100, 2)
+		cfg := limiter.Apply(&rest.Config{})
+		Expect(cfg.RateLimiter).Should(BeIdenticalTo(limiter))
+
+		for i := 0; i < 2; i++ {
+			Expect(limiter.Wait(context.Background())).Should(Succeed())
+		}
+		requests, duration := limiter.Throttled()
+		Expect(requests).Should(BeZero())
+		Expect(duration).Should(BeZero())
+
+		Expect(limiter.Wait(context.Background())).Should(Succeed())
+		limiter.Accept()
+		requests, duration = limiter.Throttled()
+		Expect(requests).Should(BeEquivalentTo(2))
+		Expect(duration).ShouldNot(BeZero())
+
+		Expect(testutil.CollectAndCount(limiter)).Should(Equal(2))
+	})
+})
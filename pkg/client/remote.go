@@ -0,0 +1,21 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+// ProxyGet sends a GET request to the HTTP port of the service via the service proxy of kube-apiserver.
+func (k *KubeClient) ProxyGet(ctx context.Context, namespace, service, port, path string, params map[string]string) ([]byte, error) {
+	clientset, err := kubernetes.NewForConfig(k.Cluster.GetConfig())
+	if err != nil {
+		return nil, err
+	}
+	body, err := clientset.CoreV1().Services(namespace).ProxyGet("http", service, port, path, params).DoRaw(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s from %s/%s: %w", path, namespace, service, err)
+	}
+	return body, nil
+}
@@ -1,6 +1,12 @@
 package config
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"text/template"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"sigs.k8s.io/yaml"
@@ -11,6 +17,19 @@ type Config struct {
 	TargetResources        []TargetResource      `json:"TargetResources,omitempty"`
 	NamespaceSelector      *metav1.LabelSelector `json:"NamespaceSelector,omitempty"`
 	EnableClusterResources bool                  `json:"EnableClusterResources,omitempty"`
+	UpdateStorm            *UpdateStorm          `json:"UpdateStorm,omitempty"`
+	Notifications          *Notifications        `json:"Notifications,omitempty"`
+	Persistence            *Persistence          `json:"Persistence,omitempty"`
+	History                *History              `json:"History,omitempty"`
+	OTLP                   *OTLP                 `json:"OTLP,omitempty"`
+}
+
+// UpdateStorm configures the detection of resources updated too frequently.
+type UpdateStorm struct {
+	// Threshold is the number of updates per minute regarded as an update storm.
+	Threshold float64 `json:"threshold"`
+	// Duration is the period during which the update rate has to exceed the threshold.
+	Duration metav1.Duration `json:"duration"`
 }
 
 type TargetResource struct {
@@ -19,6 +38,80 @@ type TargetResource struct {
 	ResourceSelector        *metav1.LabelSelector `json:"resourceSelector,omitempty"`
 }
 
+// Notifications configures the notifications sent to webhooks.
+type Notifications struct {
+	Webhooks []Webhook `json:"webhooks"`
+	// UpdateThreshold is the number of updates of a resource to send a notification. If this is 0, it is disabled.
+	UpdateThreshold int `json:"updateThreshold,omitempty"`
+	// ConflictThreshold is the number of times the manager that updates a resource has changed
+	// to send a notification of a manager conflict. If this is 0, it is disabled.
+	ConflictThreshold int `json:"conflictThreshold,omitempty"`
+	// MaxRetries is the maximum number of retries to send a notification. The default is 5.
+	MaxRetries *int `json:"maxRetries,omitempty"`
+	// Window is the period to count the updates and the manager switches against the thresholds.
+	// A notification is sent at most once per window for each resource and reason. The default is 1 hour.
+	Window *metav1.Duration `json:"window,omitempty"`
+}
+
+// Persistence configures the snapshots of the statistics restored on startup.
+type Persistence struct {
+	// Path is the file to save the snapshots. It should be on a persistent volume.
+	Path string `json:"path"`
+	// Interval is the interval to save the snapshots. The default is 1 minute.
+	Interval *metav1.Duration `json:"interval,omitempty"`
+}
+
+// History configures the database to record the events of resources.
+type History struct {
+	// Path is the SQLite database file to record the events. It should be on a persistent volume.
+	Path string `json:"path"`
+	// Retention is the period to keep the events. If this is 0, the events are kept forever.
+	Retention metav1.Duration `json:"retention,omitempty"`
+}
+
+const (
+	// OTLPProtocolGRPC exports the metrics via OTLP/gRPC.
+	OTLPProtocolGRPC = "grpc"
+	// OTLPProtocolHTTP exports the metrics via OTLP/HTTP with protobuf payloads.
+	OTLPProtocolHTTP = "http/protobuf"
+)
+
+// OTLP configures the export of the metrics to an OpenTelemetry collector.
+type OTLP struct {
+	// Endpoint is the host and port of the collector, e.g. "otel-collector:4317".
+	Endpoint string `json:"endpoint"`
+	// Protocol is "grpc" or "http/protobuf". The default is "grpc".
+	Protocol string `json:"protocol,omitempty"`
+	// Insecure disables TLS of the connection to the collector.
+	Insecure bool `json:"insecure,omitempty"`
+	// Headers are sent with every export request, e.g. for authentication.
+	Headers map[string]string `json:"headers,omitempty"`
+	// Interval is the interval to export the metrics. The default is 1 minute.
+	Interval *metav1.Duration `json:"interval,omitempty"`
+	// ClusterName is put on the metrics as the k8s.cluster.name resource attribute.
+	ClusterName string `json:"clusterName,omitempty"`
+}
+
+type Webhook struct {
+	URL string `json:"url"`
+	// Template is a Go template of the request body. If this is empty, the notification is sent as JSON.
+	Template string `json:"template,omitempty"`
+}
+
+// ParseTemplate parses the template of the webhook. It returns nil if the template is empty.
+// The template can use the json function to embed a value as a JSON literal, e.g. `{"text": {{ json .Message }}}`.
+func (w *Webhook) ParseTemplate() (*template.Template, error) {
+	if w.Template == "" {
+		return nil, nil
+	}
+	return template.New(w.URL).Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Parse(w.Template)
+}
+
 func (c *Config) SelectorFor(gvk metav1.GroupVersionKind) (nsSelector labels.Selector, resSelector labels.Selector, err error) {
 	for _, target := range c.TargetResources {
 		if target.GroupVersionKind == gvk {
@@ -55,7 +148,65 @@ func (c *Config) SelectorFor(gvk metav1.GroupVersionKind) (nsSelector labels.Sel
 
 // Validate validates the configurations.
 func (c *Config) Validate() error {
-
+	if c.UpdateStorm != nil {
+		if c.UpdateStorm.Threshold <= 0 {
+			return errors.New("updateStorm.threshold must be positive")
+		}
+		if c.UpdateStorm.Duration.Duration <= 0 {
+			return errors.New("updateStorm.duration must be positive")
+		}
+	}
+	if c.Notifications != nil {
+		if len(c.Notifications.Webhooks) == 0 {
+			return errors.New("notifications.webhooks must not be empty")
+		}
+		for _, webhook := range c.Notifications.Webhooks {
+			if _, err := url.ParseRequestURI(webhook.URL); err != nil {
+				return fmt.Errorf("invalid notifications.webhooks.url %q: %w", webhook.URL, err)
+			}
+			if _, err := webhook.ParseTemplate(); err != nil {
+				return fmt.Errorf("invalid notifications.webhooks.template: %w", err)
+			}
+		}
+		if c.Notifications.UpdateThreshold < 0 || c.Notifications.ConflictThreshold < 0 {
+			return errors.New("notifications thresholds must not be negative")
+		}
+		if c.Notifications.MaxRetries != nil && *c.Notifications.MaxRetries < 0 {
+			return errors.New("notifications.maxRetries must not be negative")
+		}
+		if c.Notifications.Window != nil && c.Notifications.Window.Duration <= 0 {
+			return errors.New("notifications.window must be positive")
+		}
+	}
+	if c.Persistence != nil {
+		if c.Persistence.Path == "" {
+			return errors.New("persistence.path must not be empty")
+		}
+		if c.Persistence.Interval != nil && c.Persistence.Interval.Duration <= 0 {
+			return errors.New("persistence.interval must be positive")
+		}
+	}
+	if c.History != nil {
+		if c.History.Path == "" {
+			return errors.New("history.path must not be empty")
+		}
+		if c.History.Retention.Duration < 0 {
+			return errors.New("history.retention must not be negative")
+		}
+	}
+	if c.OTLP != nil {
+		if c.OTLP.Endpoint == "" {
+			return errors.New("otlp.endpoint must not be empty")
+		}
+		switch c.OTLP.Protocol {
+		case "", OTLPProtocolGRPC, OTLPProtocolHTTP:
+		default:
+			return fmt.Errorf("otlp.protocol must be %q or %q", OTLPProtocolGRPC, OTLPProtocolHTTP)
+		}
+		if c.OTLP.Interval != nil && c.OTLP.Interval.Duration <= 0 {
+			return errors.New("otlp.interval must be positive")
+		}
+	}
 	return nil
 }
 
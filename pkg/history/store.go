@@ -0,0 +1,319 @@
+package history
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/zoetrope/kubbernecker/pkg/watch"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	// bufferSize is the number of events buffered before being written to the database.
+	bufferSize = 10000
+	// flushInterval is the interval to write the buffered events to the database.
+	flushInterval = time.Second
+	// pruneInterval is the interval to delete the events older than the retention period.
+	pruneInterval = 10 * time.Minute
+	// dropReportInterval is the interval to log the number of the events dropped because the buffer is full.
+	dropReportInterval = time.Minute
+)
+
+const createTables = `
+CREATE TABLE IF NOT EXISTS events (
+	time      INTEGER NOT NULL,
+	cluster   TEXT NOT NULL,
+	grp       TEXT NOT NULL,
+	version   TEXT NOT NULL,
+	kind      TEXT NOT NULL,
+	namespace TEXT NOT NULL,
+	name      TEXT NOT NULL,
+	type      TEXT NOT NULL,
+	manager   TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS events_time ON events (time);
+`
+
+// Store records the events of resources in an embedded SQLite database.
+type Store struct {
+	logger    logr.Logger
+	db        *sql.DB
+	retention time.Duration
+	events    chan watch.Event
+	dropped   atomic.Int64
+}
+
+var _ watch.EventSink = &Store{}
+
+// Open opens the database at the path, creating it if it does not exist.
+// The events older than retention are deleted periodically. If retention is 0, the events are kept forever.
+func Open(logger logr.Logger, path string, retention time.Duration) (*Store, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite does not allow concurrent writes
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(createTables); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize %s: %w", path, err)
+	}
+
+	return &Store{
+		logger:    logger,
+		db:        db,
+		retention: retention,
+		events:    make(chan watch.Event, bufferSize),
+	}, nil
+}
+
+// OpenReadOnly opens the existing database at the path to query the events.
+// Unlike Open, it fails if the database does not exist instead of creating an empty one.
+func OpenReadOnly(logger logr.Logger, path string) (*Store, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", "file:"+(&url.URL{Path: path}).EscapedPath()+"?mode=ro")
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+
+	return &Store{
+		logger: logger,
+		db:     db,
+	}, nil
+}
+
+// Record buffers the event to be written to the database. If the buffer is full, the event is dropped.
+// The number of the dropped events is logged periodically.
+func (s *Store) Record(event watch.Event) {
+	select {
+	case s.events <- event:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+// reportDropped logs the number of the events dropped since the last report.
+func (s *Store) reportDropped() {
+	if dropped := s.dropped.Swap(0); dropped > 0 {
+		s.logger.Info("history buffer is full, events are dropped", "dropped", dropped)
+	}
+}
+
+// Start writes the buffered events to the database periodically until the context is canceled.
+// The remaining events are written before returning.
+func (s *Store) Start(ctx context.Context) error {
+	flushTicker := time.NewTicker(flushInterval)
+	defer flushTicker.Stop()
+	pruneTicker := time.NewTicker(pruneInterval)
+	defer pruneTicker.Stop()
+	dropTicker := time.NewTicker(dropReportInterval)
+	defer dropTicker.Stop()
+
+	if err := s.prune(time.Now()); err != nil {
+		s.logger.Error(err, "failed to delete old events")
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			s.reportDropped()
+			return s.Flush()
+		case <-dropTicker.C:
+			s.reportDropped()
+		case <-flushTicker.C:
+			if err := s.Flush(); err != nil {
+				s.logger.Error(err, "failed to write events")
+			}
+		case <-pruneTicker.C:
+			if err := s.prune(time.Now()); err != nil {
+				s.logger.Error(err, "failed to delete old events")
+			}
+		}
+	}
+}
+
+// Flush writes the buffered events to the database in a single transaction.
+func (s *Store) Flush() error {
+	// Only Flush receives from the channel, so the buffered events can be received without blocking
+	n := len(s.events)
+	events := make([]watch.Event, 0, n)
+	for i := 0; i < n; i++ {
+		events = append(events, <-s.events)
+	}
+	if len(events) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare("INSERT INTO events (time, cluster, grp, version, kind, namespace, name, type, manager) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+	for _, e := range events {
+		_, err := stmt.Exec(e.Time.UnixNano(), e.Cluster, e.GroupVersionKind.Group, e.GroupVersionKind.Version, e.GroupVersionKind.Kind, e.Namespace, e.Name, e.Type, e.Manager)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *Store) prune(now time.Time) error {
+	if s.retention <= 0 {
+		return nil
+	}
+	res, err := s.db.Exec("DELETE FROM events WHERE time < ?", now.Add(-s.retention).UnixNano())
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n > 0 {
+		s.logger.V(1).Info("deleted old events", "count", n)
+	}
+	return nil
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Query is the condition to query the history.
+type Query struct {
+	// From and To are the range of the time of the events. Zero values mean unbounded.
+	From time.Time
+	To   time.Time
+	// GroupKinds are the groups and kinds of the resources. The kinds are compared case-insensitively.
+	// If this is empty, all resources are matched.
+	GroupKinds []schema.GroupKind
+	// Namespace is the namespace of the resources. If this is empty, all namespaces are matched.
+	Namespace string
+	// Limit is the maximum number of resources to return. If this is 0, all resources are returned.
+	Limit int
+}
+
+// Churn is the number of events of a resource in the queried range.
+type Churn struct {
+	GroupVersionKind metav1.GroupVersionKind `json:"gvk"`
+	Cluster          string                  `json:"cluster,omitempty"`
+	Namespace        string                  `json:"namespace,omitempty"`
+	Name             string                  `json:"name"`
+	AddCount         int                     `json:"add"`
+	DeleteCount      int                     `json:"delete"`
+	UpdateCount      int                     `json:"update"`
+	// Managers is the number of writes for each manager.
+	Managers map[string]int `json:"managers,omitempty"`
+}
+
+// Top returns the resources that churned most in the queried range, sorted by the number of updates.
+func (s *Store) Top(ctx context.Context, q Query) ([]*Churn, error) {
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+	if !q.From.IsZero() {
+		conditions = append(conditions, "time >= ?")
+		args = append(args, q.From.UnixNano())
+	}
+	if !q.To.IsZero() {
+		conditions = append(conditions, "time < ?")
+		args = append(args, q.To.UnixNano())
+	}
+	if len(q.GroupKinds) > 0 {
+		kinds := make([]string, 0, len(q.GroupKinds))
+		for _, gk := range q.GroupKinds {
+			kinds = append(kinds, "(grp = ? AND kind = ? COLLATE NOCASE)")
+			args = append(args, gk.Group, gk.Kind)
+		}
+		conditions = append(conditions, "("+strings.Join(kinds, " OR ")+")")
+	}
+	if q.Namespace != "" {
+		conditions = append(conditions, "namespace = ?")
+		args = append(args, q.Namespace)
+	}
+	query := "SELECT cluster, grp, version, kind, namespace, name, type, manager, COUNT(*) FROM events"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " GROUP BY cluster, grp, version, kind, namespace, name, type, manager"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// The events of a resource recorded with different versions are counted separately as in the query
+	type churnKey struct {
+		cluster, group, version, kind, namespace, name string
+	}
+	churns := make(map[churnKey]*Churn)
+	for rows.Next() {
+		var c Churn
+		var eventType, manager string
+		var count int
+		err := rows.Scan(&c.Cluster, &c.GroupVersionKind.Group, &c.GroupVersionKind.Version, &c.GroupVersionKind.Kind, &c.Namespace, &c.Name, &eventType, &manager, &count)
+		if err != nil {
+			return nil, err
+		}
+		key := churnKey{c.Cluster, c.GroupVersionKind.Group, c.GroupVersionKind.Version, c.GroupVersionKind.Kind, c.Namespace, c.Name}
+		churn, ok := churns[key]
+		if !ok {
+			churn = &c
+			churn.Managers = make(map[string]int)
+			churns[key] = churn
+		}
+		switch eventType {
+		case "add":
+			churn.AddCount += count
+		case "update":
+			churn.UpdateCount += count
+		case "delete":
+			churn.DeleteCount += count
+		}
+		if manager != "" {
+			churn.Managers[manager] += count
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make([]*Churn, 0, len(churns))
+	for _, churn := range churns {
+		result = append(result, churn)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].UpdateCount != result[j].UpdateCount {
+			return result[i].UpdateCount > result[j].UpdateCount
+		}
+		ti := result[i].AddCount + result[i].DeleteCount
+		tj := result[j].AddCount + result[j].DeleteCount
+		if ti != tj {
+			return ti > tj
+		}
+		return result[i].Namespace+"/"+result[i].Name < result[j].Namespace+"/"+result[j].Name
+	})
+	if q.Limit > 0 && len(result) > q.Limit {
+		result = result[:q.Limit]
+	}
+	return result, nil
+}
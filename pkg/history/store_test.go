@@ -0,0 +1,162 @@
+package history
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/zoetrope/kubbernecker/pkg/watch"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var _ = Describe("Test Store", func() {
+	ctx := context.Background()
+	configMap := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	deployment := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	base := time.Date(2023, 3, 1, 2, 0, 0, 0, time.UTC)
+	event := func(minutes int, gvk schema.GroupVersionKind, name, eventType, manager string) watch.Event {
+		return watch.Event{
+			Time:             base.Add(time.Duration(minutes) * time.Minute),
+			GroupVersionKind: gvk,
+			Namespace:        "default",
+			Name:             name,
+			Type:             eventType,
+			Manager:          manager,
+		}
+	}
+
+	var store *Store
+	var path string
+	BeforeEach(func() {
+		var err error
+		path = filepath.Join(GinkgoT().TempDir(), "history.db")
+		store, err = Open(ctrl.Log, path, 0)
+		Expect(err).ShouldNot(HaveOccurred())
+		DeferCleanup(store.Close)
+
+		for _, e := range []watch.Event{
+			event(-10, configMap, "before", "update", "controller"),
+			event(0, configMap, "busy", "add", "helm"),
+			event(10, configMap, "busy", "update", "controller"),
+			event(20, configMap, "busy", "update", "controller"),
+			event(30, configMap, "busy", "update", "kubectl"),
+			event(40, configMap, "quiet", "update", ""),
+			event(50, deployment, "app", "update", "controller"),
+			event(50, deployment, "app", "update", "controller"),
+			event(70, configMap, "after", "update", "controller"),
+		} {
+			store.Record(e)
+		}
+		Expect(store.Flush()).Should(Succeed())
+	})
+
+	It("should return the resources that churned most in the range", func() {
+		churns, err := store.Top(ctx, Query{From: base, To: base.Add(time.Hour)})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(churns).Should(Equal([]*Churn{
+			{
+				GroupVersionKind: metav1.GroupVersionKind{Version: "v1", Kind: "ConfigMap"},
+				Namespace:        "default",
+				Name:             "busy",
+				AddCount:         1,
+				UpdateCount:      3,
+				Managers:         map[string]int{"helm": 1, "controller": 2, "kubectl": 1},
+			},
+			{
+				GroupVersionKind: metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+				Namespace:        "default",
+				Name:             "app",
+				UpdateCount:      2,
+				Managers:         map[string]int{"controller": 2},
+			},
+			{
+				GroupVersionKind: metav1.GroupVersionKind{Version: "v1", Kind: "ConfigMap"},
+				Namespace:        "default",
+				Name:             "quiet",
+				UpdateCount:      1,
+				Managers:         map[string]int{},
+			},
+		}))
+	})
+
+	It("should filter the resources", func() {
+		churns, err := store.Top(ctx, Query{GroupKinds: []schema.GroupKind{{Group: "apps", Kind: "deployment"}, {Group: "extensions", Kind: "deployment"}}})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(churns).Should(HaveLen(1))
+		Expect(churns[0].Name).Should(Equal("app"))
+
+		churns, err = store.Top(ctx, Query{GroupKinds: []schema.GroupKind{{Kind: "Deployment"}}})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(churns).Should(BeEmpty())
+
+		churns, err = store.Top(ctx, Query{Namespace: "other"})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(churns).Should(BeEmpty())
+
+		churns, err = store.Top(ctx, Query{Limit: 2})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(churns).Should(HaveLen(2))
+	})
+
+	It("should count the events recorded with different versions separately", func() {
+		store.Record(event(50, schema.GroupVersionKind{Group: "apps", Version: "v1beta1", Kind: "Deployment"}, "app", "update", "controller"))
+		Expect(store.Flush()).Should(Succeed())
+
+		churns, err := store.Top(ctx, Query{GroupKinds: []schema.GroupKind{{Group: "apps", Kind: "Deployment"}}})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(churns).Should(HaveLen(2))
+		Expect(churns[0].GroupVersionKind.Version).Should(Equal("v1"))
+		Expect(churns[0].UpdateCount).Should(Equal(2))
+		Expect(churns[1].GroupVersionKind.Version).Should(Equal("v1beta1"))
+		Expect(churns[1].UpdateCount).Should(Equal(1))
+	})
+
+	It("should open only the existing database read-only", func() {
+		reader, err := OpenReadOnly(ctrl.Log, path)
+		Expect(err).ShouldNot(HaveOccurred())
+		defer reader.Close()
+		churns, err := reader.Top(ctx, Query{Limit: 1})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(churns).Should(HaveLen(1))
+		Expect(churns[0].Name).Should(Equal("busy"))
+		_, err = reader.db.Exec("DELETE FROM events")
+		Expect(err).Should(HaveOccurred())
+
+		missing := filepath.Join(filepath.Dir(path), "missing.db")
+		_, err = OpenReadOnly(ctrl.Log, missing)
+		Expect(err).Should(HaveOccurred())
+		_, err = os.Stat(missing)
+		Expect(os.IsNotExist(err)).Should(BeTrue())
+	})
+
+	It("should delete the events older than the retention", func() {
+		store.retention = time.Hour
+		Expect(store.prune(base.Add(time.Hour + 30*time.Minute))).Should(Succeed())
+		churns, err := store.Top(ctx, Query{})
+		Expect(err).ShouldNot(HaveOccurred())
+		names := make([]string, 0, len(churns))
+		for _, churn := range churns {
+			names = append(names, churn.Name)
+		}
+		Expect(names).Should(ConsistOf("busy", "quiet", "app", "after"))
+		Expect(churns[0].Name).Should(Equal("app"))
+	})
+
+	It("should count the events dropped when the buffer is full", func() {
+		for i := 0; i < bufferSize+5; i++ {
+			store.Record(event(i, configMap, "busy", "update", "controller"))
+		}
+		Expect(store.dropped.Load()).Should(BeEquivalentTo(5))
+		store.reportDropped()
+		Expect(store.dropped.Load()).Should(BeZero())
+
+		Expect(store.Flush()).Should(Succeed())
+		store.Record(event(0, configMap, "busy", "update", "controller"))
+		Expect(store.dropped.Load()).Should(BeZero())
+	})
+})
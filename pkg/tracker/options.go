@@ -0,0 +1,73 @@
+package tracker
+
+import (
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// defaultEventBuffer is the default size of the channels of the subscriptions.
+const defaultEventBuffer = 100
+
+type options struct {
+	logger          logr.Logger
+	cluster         string
+	resources       []schema.GroupVersionKind
+	nsSelector      labels.Selector
+	resSelector     labels.Selector
+	includeExisting bool
+	eventBuffer     int
+}
+
+// Option configures a Tracker.
+type Option func(*options)
+
+// WithLogger sets the logger of the Tracker. The default is a logger discarding all messages.
+func WithLogger(logger logr.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithClusterName sets the name of the cluster put on the statistics and the events.
+func WithClusterName(name string) Option {
+	return func(o *options) {
+		o.cluster = name
+	}
+}
+
+// WithResources adds the resource types to track. At least one resource type is required.
+func WithResources(gvks ...schema.GroupVersionKind) Option {
+	return func(o *options) {
+		o.resources = append(o.resources, gvks...)
+	}
+}
+
+// WithNamespaceSelector sets the selector of the namespaces to which the tracked resources belong.
+// The labels of the namespaces are read from the cache, so it needs the permissions to list and watch namespaces.
+func WithNamespaceSelector(selector labels.Selector) Option {
+	return func(o *options) {
+		o.nsSelector = selector
+	}
+}
+
+// WithResourceSelector sets the selector of the tracked resources by their labels.
+func WithResourceSelector(selector labels.Selector) Option {
+	return func(o *options) {
+		o.resSelector = selector
+	}
+}
+
+// WithIncludeExisting includes the resources existing at the start of tracking in the statistics with zero counts.
+func WithIncludeExisting() Option {
+	return func(o *options) {
+		o.includeExisting = true
+	}
+}
+
+// WithEventBuffer sets the size of the channels of the subscriptions. The default is 100.
+func WithEventBuffer(size int) Option {
+	return func(o *options) {
+		o.eventBuffer = size
+	}
+}
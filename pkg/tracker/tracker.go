@@ -0,0 +1,233 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/zoetrope/kubbernecker/pkg/watch"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrlcache "sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// Tracker counts the events of the resources notified by the informers in the cache of controller-runtime.
+type Tracker struct {
+	cache   ctrlcache.Cache
+	options options
+
+	mu          sync.RWMutex
+	watchers    []*watch.Watcher
+	subscribers map[*subscription]struct{}
+	stopped     bool
+	// done is closed when the Tracker is stopped
+	done      chan struct{}
+	collector *watch.Collector
+}
+
+type subscription struct {
+	ch chan watch.Event
+}
+
+// New creates a Tracker that watches the resources with the informers of cache.
+// The cache has to be started separately, and Start has to be called to start tracking.
+func New(cache ctrlcache.Cache, opts ...Option) (*Tracker, error) {
+	o := options{
+		logger:      logr.Discard(),
+		nsSelector:  labels.Everything(),
+		resSelector: labels.Everything(),
+		eventBuffer: defaultEventBuffer,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if len(o.resources) == 0 {
+		return nil, errors.New("no resources to track")
+	}
+	if o.eventBuffer < 0 {
+		return nil, errors.New("event buffer must not be negative")
+	}
+
+	t := &Tracker{
+		cache:       cache,
+		options:     o,
+		subscribers: make(map[*subscription]struct{}),
+		done:        make(chan struct{}),
+	}
+	t.collector = watch.NewCollector(t.Watchers)
+	return t, nil
+}
+
+// NewForManager creates a Tracker that watches the resources with the cache of mgr.
+// The Tracker is added to mgr, so it is started with mgr.
+func NewForManager(mgr manager.Manager, opts ...Option) (*Tracker, error) {
+	t, err := New(mgr.GetCache(), opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := mgr.Add(t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Start starts tracking the resources, and blocks until ctx is done.
+// The channels of the subscriptions are closed when it returns.
+func (t *Tracker) Start(ctx context.Context) error {
+	defer t.stop()
+
+	seen := make(map[schema.GroupVersionKind]bool)
+	for _, gvk := range t.options.resources {
+		if seen[gvk] {
+			continue
+		}
+		seen[gvk] = true
+
+		source := watch.NewCacheSource(t.cache, gvk)
+		watcher := watch.NewWatcherWithSource(t.options.logger, source, t.options.cluster, gvk, t.options.resSelector, t.options.includeExisting)
+		watcher.SetNamespaceSelector(t.cache, t.options.nsSelector)
+		watcher.SetEventSink(sink{t})
+		if err := watcher.Start(ctx); err != nil {
+			return err
+		}
+
+		t.mu.Lock()
+		t.watchers = append(t.watchers, watcher)
+		t.mu.Unlock()
+	}
+
+	<-ctx.Done()
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. The Tracker runs in all replicas,
+// because the informers in the cache do.
+func (t *Tracker) NeedLeaderElection() bool {
+	return false
+}
+
+func (t *Tracker) stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stopped {
+		return
+	}
+	t.stopped = true
+	close(t.done)
+	for s := range t.subscribers {
+		close(s.ch)
+		delete(t.subscribers, s)
+	}
+	for _, watcher := range t.watchers {
+		_ = watcher.Stop()
+	}
+}
+
+// HasSynced returns true if the informers of all the resources have synced.
+func (t *Tracker) HasSynced() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if len(t.watchers) == 0 {
+		return false
+	}
+	for _, watcher := range t.watchers {
+		if !watcher.HasSynced() {
+			return false
+		}
+	}
+	return true
+}
+
+// Watchers returns the watchers of the tracked resources.
+func (t *Tracker) Watchers() []*watch.Watcher {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return append([]*watch.Watcher(nil), t.watchers...)
+}
+
+// Watcher returns the watcher of the resource type.
+func (t *Tracker) Watcher(gvk schema.GroupVersionKind) (*watch.Watcher, bool) {
+	for _, watcher := range t.Watchers() {
+		if watcher.GroupVersionKind() == gvk {
+			return watcher, true
+		}
+	}
+	return nil, false
+}
+
+// Statistics returns the statistics of the tracked resources, sorted by GroupVersionKind.
+func (t *Tracker) Statistics() []*watch.Statistics {
+	watchers := t.Watchers()
+	result := make([]*watch.Statistics, 0, len(watchers))
+	for _, watcher := range watchers {
+		result = append(result, watcher.Statistics())
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].GroupVersionKind.String() < result[j].GroupVersionKind.String()
+	})
+	return result
+}
+
+// Subscribe returns a channel receiving the events counted by the Tracker.
+// The channel is closed when ctx is done or the Tracker stops. The events are dropped while the channel is full,
+// so that a slow subscriber does not block the informers.
+func (t *Tracker) Subscribe(ctx context.Context) <-chan watch.Event {
+	s := &subscription{
+		ch: make(chan watch.Event, t.options.eventBuffer),
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stopped {
+		close(s.ch)
+		return s.ch
+	}
+	t.subscribers[s] = struct{}{}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-t.done:
+			// The channel has been closed by stop
+			return
+		}
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if _, ok := t.subscribers[s]; ok {
+			close(s.ch)
+			delete(t.subscribers, s)
+		}
+	}()
+	return s.ch
+}
+
+// Collector returns a prometheus.Collector exposing the statistics of the tracked resources
+// in the same metrics as kubbernecker-metrics.
+func (t *Tracker) Collector() prometheus.Collector {
+	return t.collector
+}
+
+// sink sends the events counted by the watchers to the subscribers.
+type sink struct {
+	t *Tracker
+}
+
+func (s sink) Record(event watch.Event) {
+	s.t.mu.RLock()
+	defer s.t.mu.RUnlock()
+
+	for sub := range s.t.subscribers {
+		select {
+		case sub.ch <- event:
+		default:
+			s.t.options.logger.V(1).Info("drop event for slow subscriber", "gvk", event.GroupVersionKind.String(), "namespace", event.Namespace, "name", event.Name)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package tracker
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/zoetrope/kubbernecker/pkg/watch"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	toolscache "k8s.io/client-go/tools/cache"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlcache "sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllertest"
+)
+
+// fakeInformer is a FakeInformer whose event handlers can be removed.
+type fakeInformer struct {
+	controllertest.FakeInformer
+	mu       sync.Mutex
+	handlers map[*toolscache.ResourceEventHandlerFuncs]toolscache.ResourceEventHandler
+}
+
+func (f *fakeInformer) AddEventHandler(handler toolscache.ResourceEventHandler) (toolscache.ResourceEventHandlerRegistration, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	reg := &toolscache.ResourceEventHandlerFuncs{}
+	f.handlers[reg] = handler
+	return reg, nil
+}
+
+func (f *fakeInformer) RemoveEventHandler(reg toolscache.ResourceEventHandlerRegistration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.handlers, reg.(*toolscache.ResourceEventHandlerFuncs))
+	return nil
+}
+
+func (f *fakeInformer) Handlers() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.handlers)
+}
+
+func (f *fakeInformer) each(fn func(toolscache.ResourceEventHandler)) {
+	f.mu.Lock()
+	handlers := make([]toolscache.ResourceEventHandler, 0, len(f.handlers))
+	for _, h := range f.handlers {
+		handlers = append(handlers, h)
+	}
+	f.mu.Unlock()
+	for _, h := range handlers {
+		fn(h)
+	}
+}
+
+func (f *fakeInformer) Add(obj metav1.Object) {
+	f.each(func(h toolscache.ResourceEventHandler) { h.OnAdd(obj) })
+}
+
+func (f *fakeInformer) Update(oldObj, newObj metav1.Object) {
+	f.each(func(h toolscache.ResourceEventHandler) { h.OnUpdate(oldObj, newObj) })
+}
+
+func (f *fakeInformer) Delete(obj metav1.Object) {
+	f.each(func(h toolscache.ResourceEventHandler) { h.OnDelete(obj) })
+}
+
+// fakeCache returns a fake informer for each GroupVersionKind of PartialObjectMetadata,
+// and the namespaces with labels.
+type fakeCache struct {
+	*informertest.FakeInformers
+	mu         sync.Mutex
+	informers  map[schema.GroupVersionKind]*fakeInformer
+	namespaces map[string]map[string]string
+	gets       int
+}
+
+func (c *fakeCache) GetInformer(_ context.Context, obj ctrlclient.Object) (ctrlcache.Informer, error) {
+	return c.informer(obj.GetObjectKind().GroupVersionKind()), nil
+}
+
+func (c *fakeCache) Get(_ context.Context, key ctrlclient.ObjectKey, obj ctrlclient.Object, _ ...ctrlclient.GetOption) error {
+	c.mu.Lock()
+	c.gets++
+	c.mu.Unlock()
+	obj.SetLabels(c.namespaces[key.Name])
+	return nil
+}
+
+func (c *fakeCache) Gets() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.gets
+}
+
+func (c *fakeCache) informer(gvk schema.GroupVersionKind) *fakeInformer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.informers[gvk]; !ok {
+		c.informers[gvk] = &fakeInformer{
+			handlers: make(map[*toolscache.ResourceEventHandlerFuncs]toolscache.ResourceEventHandler),
+		}
+	}
+	return c.informers[gvk]
+}
+
+var _ = Describe("Test Tracker", func() {
+	configMaps := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	secrets := schema.GroupVersionKind{Version: "v1", Kind: "Secret"}
+	var cache *fakeCache
+	var ctx context.Context
+	var cancel context.CancelFunc
+
+	newMeta := func(namespace, name, resourceVersion string) *metav1.PartialObjectMetadata {
+		return &metav1.PartialObjectMetadata{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:       namespace,
+				Name:            name,
+				UID:             types.UID(namespace + "-" + name),
+				ResourceVersion: resourceVersion,
+				Labels:          map[string]string{"app": name},
+			},
+		}
+	}
+	startTracker := func(opts ...Option) *Tracker {
+		tracker, err := New(cache, append(opts, WithLogger(ctrl.Log.WithName("tracker-test")))...)
+		Expect(err).ShouldNot(HaveOccurred())
+		go func() {
+			defer GinkgoRecover()
+			Expect(tracker.Start(ctx)).Should(Succeed())
+		}()
+		Eventually(tracker.Watchers).ShouldNot(BeEmpty())
+		return tracker
+	}
+
+	BeforeEach(func() {
+		cache = &fakeCache{
+			FakeInformers: &informertest.FakeInformers{},
+			informers:     make(map[schema.GroupVersionKind]*fakeInformer),
+			namespaces: map[string]map[string]string{
+				"admin-ns": {"role": "admin"},
+				"user-ns":  {},
+			},
+		}
+		ctx, cancel = context.WithCancel(context.Background())
+		DeferCleanup(func() { cancel() })
+	})
+
+	It("should require resources", func() {
+		_, err := New(cache)
+		Expect(err).Should(HaveOccurred())
+	})
+
+	It("should count the events with the selectors", func() {
+		tracker := startTracker(
+			WithResources(configMaps),
+			WithClusterName("test-cluster"),
+			WithNamespaceSelector(labels.SelectorFromSet(labels.Set{"role": "admin"})),
+			WithResourceSelector(labels.SelectorFromSet(labels.Set{"app": "web"})),
+		)
+		informer := cache.informer(configMaps)
+		informer.Add(newMeta("admin-ns", "existing", "1"))
+		informer.Synced = true
+		Eventually(tracker.HasSynced).Should(BeTrue())
+
+		informer.Add(newMeta("admin-ns", "web", "2"))
+		informer.Update(newMeta("admin-ns", "web", "2"), newMeta("admin-ns", "web", "3"))
+		informer.Add(newMeta("admin-ns", "db", "4"))
+		informer.Add(newMeta("user-ns", "web", "5"))
+
+		statistics := tracker.Statistics()
+		Expect(statistics).Should(HaveLen(1))
+		Expect(statistics[0].Cluster).Should(Equal("test-cluster"))
+		Expect(statistics[0].Namespaces).Should(MatchAllKeys(Keys{
+			"admin-ns": PointTo(MatchFields(IgnoreExtras, Fields{
+				"Resources": MatchAllKeys(Keys{
+					"web": PointTo(MatchFields(IgnoreExtras, Fields{
+						"AddCount":    Equal(1),
+						"UpdateCount": Equal(1),
+					})),
+				}),
+			})),
+		}))
+	})
+
+	It("should include the existing resources", func() {
+		tracker := startTracker(WithResources(configMaps), WithIncludeExisting())
+		cache.informer(configMaps).Add(newMeta("user-ns", "existing", "1"))
+
+		Expect(tracker.Statistics()[0].Namespaces["user-ns"].Resources).Should(HaveKeyWithValue("existing", PointTo(MatchFields(IgnoreExtras, Fields{
+			"AddCount": Equal(0),
+		}))))
+	})
+
+	It("should deliver the events to the subscribers", func() {
+		tracker := startTracker(WithResources(configMaps), WithEventBuffer(1))
+		informer := cache.informer(configMaps)
+		informer.Synced = true
+
+		subCtx, unsubscribe := context.WithCancel(ctx)
+		events := tracker.Subscribe(subCtx)
+		informer.Add(newMeta("user-ns", "web", "1"))
+		// The channel is full, so the event is dropped
+		informer.Delete(newMeta("user-ns", "web", "1"))
+
+		Expect(<-events).Should(MatchFields(IgnoreExtras, Fields{
+			"GroupVersionKind": Equal(configMaps),
+			"Namespace":        Equal("user-ns"),
+			"Name":             Equal("web"),
+			"Type":             Equal("add"),
+		}))
+		unsubscribe()
+		Eventually(events).Should(BeClosed())
+
+		events = tracker.Subscribe(ctx)
+		cancel()
+		Eventually(events).Should(BeClosed())
+		Expect(tracker.Subscribe(context.Background())).Should(BeClosed())
+	})
+
+	It("should not leak the subscriptions after stopping", func() {
+		tracker := startTracker(WithResources(configMaps))
+		goroutines := runtime.NumGoroutine()
+
+		subscriptions := make([]<-chan watch.Event, 0, 100)
+		for i := 0; i < 100; i++ {
+			subscriptions = append(subscriptions, tracker.Subscribe(context.Background()))
+		}
+		cancel()
+		for _, events := range subscriptions {
+			Eventually(events).Should(BeClosed())
+		}
+		// The goroutines waiting for the contexts of the subscriptions exit with the Tracker
+		Eventually(runtime.NumGoroutine).Should(BeNumerically("<", goroutines+10))
+	})
+
+	It("should not count the events after stopping", func() {
+		tracker := startTracker(
+			WithResources(configMaps),
+			WithNamespaceSelector(labels.SelectorFromSet(labels.Set{"role": "admin"})),
+		)
+		informer := cache.informer(configMaps)
+		informer.Synced = true
+		Expect(informer.Handlers()).Should(Equal(1))
+
+		informer.Add(newMeta("admin-ns", "web", "1"))
+		gets := cache.Gets()
+		Expect(gets).ShouldNot(BeZero())
+
+		cancel()
+		Eventually(informer.Handlers).Should(BeZero())
+		informer.Update(newMeta("admin-ns", "web", "1"), newMeta("admin-ns", "web", "2"))
+		informer.Add(newMeta("user-ns", "db", "3"))
+
+		Expect(cache.Gets()).Should(Equal(gets))
+		Expect(tracker.Statistics()[0].Namespaces).Should(MatchAllKeys(Keys{
+			"admin-ns": PointTo(MatchFields(IgnoreExtras, Fields{
+				"Resources": MatchAllKeys(Keys{
+					"web": PointTo(MatchFields(IgnoreExtras, Fields{
+						"AddCount":    Equal(1),
+						"UpdateCount": Equal(0),
+					})),
+				}),
+			})),
+		}))
+	})
+
+	It("should expose the statistics as Prometheus metrics", func() {
+		tracker := startTracker(WithResources(configMaps, secrets, configMaps))
+		Eventually(tracker.Watchers).Should(HaveLen(2))
+		_, ok := tracker.Watcher(secrets)
+		Expect(ok).Should(BeTrue())
+
+		informer := cache.informer(configMaps)
+		informer.Synced = true
+		informer.Add(newMeta("user-ns", "web", "1"))
+
+		expected := `
+# HELP kubbernecker_resource_events_total Total number of events for Kubernetes resources
+# TYPE kubbernecker_resource_events_total counter
+kubbernecker_resource_events_total{event_type="add",group="",kind="ConfigMap",namespace="user-ns",resource_name="web",version="v1"} 1
+kubbernecker_resource_events_total{event_type="delete",group="",kind="ConfigMap",namespace="user-ns",resource_name="web",version="v1"} 0
+kubbernecker_resource_events_total{event_type="update",group="",kind="ConfigMap",namespace="user-ns",resource_name="web",version="v1"} 0
+# HELP kubbernecker_watcher_synced Whether the informer of the watcher has synced. The value is 1 if synced, 0 otherwise
+# TYPE kubbernecker_watcher_synced gauge
+kubbernecker_watcher_synced{group="",kind="ConfigMap",version="v1"} 1
+kubbernecker_watcher_synced{group="",kind="Secret",version="v1"} 0
+`
+		err := testutil.CollectAndCompare(tracker.Collector(), strings.NewReader(expected), "kubbernecker_resource_events_total", "kubbernecker_watcher_synced")
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+})
@@ -2,6 +2,8 @@ package watch
 
 import (
 	"context"
+	"errors"
+	"reflect"
 	"sync"
 	"time"
 
@@ -9,58 +11,250 @@ import (
 	"github.com/zoetrope/kubbernecker/pkg/client"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
-	"k8s.io/client-go/tools/cache"
 )
 
 type BlameWatcher struct {
-	logger   logr.Logger
-	kube     *client.KubeClient
-	gvk      schema.GroupVersionKind
-	resource string
+	logger    logr.Logger
+	source    EventSource
+	gvk       schema.GroupVersionKind
+	namespace string
+	resource  string
+
+	startTime       time.Time
+	resourceVersion string
 
 	mu         sync.RWMutex
 	statistics BlameStatistics
 }
 
-func NewBlameWatcher(logger logr.Logger, kube *client.KubeClient, gvk schema.GroupVersionKind, resource string) *BlameWatcher {
+// NewBlameWatcher creates a BlameWatcher that watches the resource with the informer in the cache of the cluster.
+func NewBlameWatcher(logger logr.Logger, kube *client.KubeClient, gvk schema.GroupVersionKind, namespace string, resource string) *BlameWatcher {
+	var source EventSource
+	var cluster string
+	if kube != nil {
+		source = NewCacheSource(kube.Cluster.GetCache(), gvk)
+		cluster = kube.Name
+	}
+	return NewBlameWatcherWithSource(logger, source, cluster, gvk, namespace, resource)
+}
+
+// NewBlameWatcherWithSource creates a BlameWatcher that attributes the events delivered by the source.
+// If source is nil, the events have to be passed to OnEvent directly.
+func NewBlameWatcherWithSource(logger logr.Logger, source EventSource, cluster string, gvk schema.GroupVersionKind, namespace string, resource string) *BlameWatcher {
 	statistics := BlameStatistics{}
 	statistics.Managers = make(map[string]*ManagerStatistics)
 	statistics.LatestUpdate = time.Now()
+	statistics.Cluster = cluster
 
 	return &BlameWatcher{
 		logger:     logger,
-		kube:       kube,
+		source:     source,
 		statistics: statistics,
 		gvk:        gvk,
+		namespace:  namespace,
 		resource:   resource,
 	}
 }
 
-func (w *BlameWatcher) collect(obj interface{}) {
-	meta := obj.(*metav1.PartialObjectMetadata)
+func (w *BlameWatcher) isTarget(meta *metav1.PartialObjectMetadata) bool {
+	if meta.Namespace != w.namespace || meta.Name != w.resource {
+		w.logger.V(10).Info("no target", "namespace", meta.Namespace, "res", meta.Name)
+		return false
+	}
+	return true
+}
+
+// OnEvent attributes the event delivered by the source.
+func (w *BlameWatcher) OnEvent(event *SourceEvent) {
+	if event.Object == nil {
+		return
+	}
+	now := event.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+	w.mu.Lock()
+	if w.startTime.IsZero() {
+		// The events are delivered without Start (e.g. replaying a recorded session), which starts with the initial list
+		w.startTime = now
+		w.statistics.LatestUpdate = now
+	}
+	w.mu.Unlock()
+
+	if event.Type == "delete" {
+		w.delete(event.Object, now)
+		return
+	}
+	w.collect(event.OldObject, event.Object, event.Type == "add" && event.Initial)
+}
+
+// OnRelist does nothing, because relists do not affect the attribution.
+func (w *BlameWatcher) OnRelist() {}
+
+// OnWatchError does nothing, because watch errors do not affect the attribution.
+func (w *BlameWatcher) OnWatchError(err error) {}
+
+// collect attributes a write of the resource. existing is true if the resource is listed at the start of watching.
+func (w *BlameWatcher) collect(oldObj, meta *metav1.PartialObjectMetadata, existing bool) {
+	if !w.isTarget(meta) {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if meta.ResourceVersion == w.resourceVersion {
+		// Resync or duplicated event, nothing has been written
+		return
+	}
+	w.resourceVersion = meta.ResourceVersion
+
+	if meta.UID != w.statistics.UID {
+		w.recreate(meta, existing)
+		return
+	}
+
+	var oldFields []metav1.ManagedFieldsEntry
+	if oldObj != nil {
+		oldFields = oldObj.ManagedFields
+	}
+	manager, latest := attributeWrite(oldFields, meta.ManagedFields)
+	if manager == "" {
+		w.logger.V(3).Info("unattributed update", "resourceVersion", meta.ResourceVersion)
+		w.statistics.Unattributed += 1
+		return
+	}
+	if _, ok := w.statistics.Managers[manager]; !ok {
+		w.statistics.Managers[manager] = &ManagerStatistics{}
+	}
+	w.statistics.Managers[manager].UpdateCount += 1
+	if latest.After(w.statistics.LatestUpdate) {
+		w.statistics.LatestUpdate = latest
+	}
+}
+
+// recreate starts counting a new incarnation of the resource and keeps the previous one in the history.
+func (w *BlameWatcher) recreate(meta *metav1.PartialObjectMetadata, existing bool) {
+	if w.statistics.UID != "" {
+		w.statistics.History = append(w.statistics.History, w.statistics.IncarnationStatistics)
+	}
 
-	if meta.Name != w.resource {
-		w.logger.V(10).Info("no target", "res", meta.Name)
+	created := meta.CreationTimestamp.Time
+	incarnation := IncarnationStatistics{
+		UID:          meta.UID,
+		CreatedAt:    &created,
+		Managers:     make(map[string]*ManagerStatistics),
+		LatestUpdate: created,
+	}
+	if existing {
+		// The resource was listed at the start of watching, so it is unknown who created it
+		incarnation.LatestUpdate = w.startTime
+	} else {
+		manager, _ := attributeWrite(nil, meta.ManagedFields)
+		incarnation.CreatedBy = manager
+	}
+	w.logger.V(3).Info("new incarnation", "uid", meta.UID, "createdAt", created, "createdBy", incarnation.CreatedBy)
+	w.statistics.IncarnationStatistics = incarnation
+}
+
+func (w *BlameWatcher) delete(meta *metav1.PartialObjectMetadata, deleted time.Time) {
+	if !w.isTarget(meta) {
 		return
 	}
 
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
-	latest := w.statistics.LatestUpdate
-	for _, field := range meta.ManagedFields {
+	if meta.UID != w.statistics.UID || w.statistics.DeletedAt != nil {
+		return
+	}
+	w.logger.V(3).Info("deleted", "uid", meta.UID, "deletedAt", deleted)
+	w.statistics.DeletedAt = &deleted
+}
+
+// attributeWrite compares the managedFields before and after a single write and returns the manager
+// that performed it. An empty manager is returned if the write cannot be attributed to exactly one manager.
+func attributeWrite(oldFields, newFields []metav1.ManagedFieldsEntry) (string, time.Time) {
+	previous := make(map[string]metav1.ManagedFieldsEntry, len(oldFields))
+	for _, field := range oldFields {
+		previous[managedFieldsKey(field)] = field
+	}
+
+	var manager string
+	var latest time.Time
+	ambiguous := false
+	for _, field := range newFields {
 		if field.Time == nil {
 			continue
 		}
-		if (*field.Time).Time.After(w.statistics.LatestUpdate) {
-			if _, ok := w.statistics.Managers[field.Manager]; !ok {
-				w.statistics.Managers[field.Manager] = &ManagerStatistics{}
-			}
-			latest = (*field.Time).Time
-			w.statistics.Managers[field.Manager].UpdateCount += 1
+		if old, ok := previous[managedFieldsKey(field)]; ok && old.Time != nil && old.Time.Equal(field.Time) {
+			continue
+		}
+		// A single write may also touch the entries of other managers (e.g. taking ownership of their fields),
+		// but only the writer's own entry gets the latest timestamp.
+		switch {
+		case manager == "" || field.Time.Time.After(latest):
+			manager = field.Manager
+			latest = field.Time.Time
+			ambiguous = false
+		case field.Time.Time.Equal(latest) && field.Manager != manager:
+			ambiguous = true
+		}
+	}
+	if ambiguous {
+		return "", latest
+	}
+	if manager == "" && oldFields != nil {
+		// The timestamps have a resolution of a second, so they are not changed by the writes within the same second
+		return attributeWriteInSameSecond(previous, newFields)
+	}
+	return manager, latest
+}
+
+// attributeWriteInSameSecond attributes a write that did not change any timestamps of managedFields.
+// The write is attributed to the only manager whose fields were changed, or the only manager with Update operation
+// if no fields were changed.
+func attributeWriteInSameSecond(previous map[string]metav1.ManagedFieldsEntry, newFields []metav1.ManagedFieldsEntry) (string, time.Time) {
+	var changed, updaters []metav1.ManagedFieldsEntry
+	for _, field := range newFields {
+		if old, ok := previous[managedFieldsKey(field)]; !ok || !reflect.DeepEqual(old.FieldsV1, field.FieldsV1) {
+			changed = append(changed, field)
+		}
+		if field.Operation == metav1.ManagedFieldsOperationUpdate {
+			updaters = append(updaters, field)
+		}
+	}
+
+	var field metav1.ManagedFieldsEntry
+	switch {
+	case singleManager(changed):
+		field = changed[0]
+	case len(changed) == 0 && singleManager(updaters):
+		field = updaters[0]
+	default:
+		return "", time.Time{}
+	}
+	if field.Time == nil {
+		return field.Manager, time.Time{}
+	}
+	return field.Manager, field.Time.Time
+}
+
+// singleManager returns true if all the entries belong to one manager (e.g. the main resource and the status).
+func singleManager(fields []metav1.ManagedFieldsEntry) bool {
+	if len(fields) == 0 {
+		return false
+	}
+	for _, field := range fields[1:] {
+		if field.Manager != fields[0].Manager {
+			return false
 		}
 	}
-	w.statistics.LatestUpdate = latest
+	return true
+}
+
+func managedFieldsKey(field metav1.ManagedFieldsEntry) string {
+	return field.Manager + "/" + string(field.Operation) + "/" + field.Subresource
 }
 
 func (w *BlameWatcher) Statistics() *BlameStatistics {
@@ -70,25 +264,19 @@ func (w *BlameWatcher) Statistics() *BlameStatistics {
 	return w.statistics.DeepCopy()
 }
 
+// HasSynced returns true if the source has delivered the initial list of the resources.
+func (w *BlameWatcher) HasSynced() bool {
+	return w.source != nil && w.source.HasSynced()
+}
+
 func (w *BlameWatcher) Start(ctx context.Context) error {
 	w.logger.Info("start watcher")
+	if w.source == nil {
+		return errors.New("no event source")
+	}
+	w.mu.Lock()
+	w.startTime = time.Now()
+	w.mu.Unlock()
 
-	meta := &metav1.PartialObjectMetadata{}
-	meta.SetGroupVersionKind(w.gvk)
-	informer, err := w.kube.Cluster.GetCache().GetInformer(ctx, meta)
-	if err != nil {
-		return err
-	}
-	_, err = informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc: func(obj interface{}) {
-			w.collect(obj)
-		},
-		UpdateFunc: func(oldObj, newObj interface{}) {
-			w.collect(newObj)
-		},
-		DeleteFunc: func(obj interface{}) {
-		},
-	})
-
-	return err
+	return w.source.Start(ctx, w)
 }
@@ -0,0 +1,172 @@
+package watch
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var _ = Describe("Test BlameWatcher", func() {
+	base := time.Date(2023, 2, 17, 22, 25, 20, 0, time.UTC)
+	entry := func(manager string, operation metav1.ManagedFieldsOperationType, sec int) metav1.ManagedFieldsEntry {
+		t := metav1.NewTime(base.Add(time.Duration(sec) * time.Second))
+		return metav1.ManagedFieldsEntry{
+			Manager:   manager,
+			Operation: operation,
+			Time:      &t,
+		}
+	}
+	withFields := func(field metav1.ManagedFieldsEntry, fields string) metav1.ManagedFieldsEntry {
+		field.FieldsV1 = &metav1.FieldsV1{Raw: []byte(fields)}
+		return field
+	}
+
+	DescribeTable("attributing a write to a manager",
+		func(oldFields, newFields []metav1.ManagedFieldsEntry, expected string) {
+			manager, _ := attributeWrite(oldFields, newFields)
+			Expect(manager).Should(Equal(expected))
+		},
+		Entry("new manager",
+			[]metav1.ManagedFieldsEntry{entry("manager1", metav1.ManagedFieldsOperationUpdate, 0)},
+			[]metav1.ManagedFieldsEntry{entry("manager1", metav1.ManagedFieldsOperationUpdate, 0), entry("manager2", metav1.ManagedFieldsOperationUpdate, 1)},
+			"manager2",
+		),
+		Entry("existing manager with updated timestamp",
+			[]metav1.ManagedFieldsEntry{entry("manager1", metav1.ManagedFieldsOperationUpdate, 0), entry("manager2", metav1.ManagedFieldsOperationUpdate, 1)},
+			[]metav1.ManagedFieldsEntry{entry("manager1", metav1.ManagedFieldsOperationUpdate, 2), entry("manager2", metav1.ManagedFieldsOperationUpdate, 1)},
+			"manager1",
+		),
+		Entry("timestamps of several managers are bumped by a single write",
+			[]metav1.ManagedFieldsEntry{entry("manager1", metav1.ManagedFieldsOperationUpdate, 0), entry("manager2", metav1.ManagedFieldsOperationApply, 1)},
+			[]metav1.ManagedFieldsEntry{entry("manager1", metav1.ManagedFieldsOperationUpdate, 2), entry("manager2", metav1.ManagedFieldsOperationApply, 3)},
+			"manager2",
+		),
+		Entry("same manager with a different operation",
+			[]metav1.ManagedFieldsEntry{entry("manager1", metav1.ManagedFieldsOperationUpdate, 0)},
+			[]metav1.ManagedFieldsEntry{entry("manager1", metav1.ManagedFieldsOperationUpdate, 0), entry("manager1", metav1.ManagedFieldsOperationApply, 1)},
+			"manager1",
+		),
+		Entry("timestamp is not changed within the same second by the only Update manager",
+			[]metav1.ManagedFieldsEntry{entry("manager1", metav1.ManagedFieldsOperationUpdate, 0), entry("manager2", metav1.ManagedFieldsOperationApply, 0)},
+			[]metav1.ManagedFieldsEntry{entry("manager1", metav1.ManagedFieldsOperationUpdate, 0), entry("manager2", metav1.ManagedFieldsOperationApply, 0)},
+			"manager1",
+		),
+		Entry("fields of a manager are changed within the same second",
+			[]metav1.ManagedFieldsEntry{withFields(entry("manager1", metav1.ManagedFieldsOperationUpdate, 0), `{"f:data":{"f:a":{}}}`), withFields(entry("manager2", metav1.ManagedFieldsOperationUpdate, 0), `{"f:data":{"f:b":{}}}`)},
+			[]metav1.ManagedFieldsEntry{withFields(entry("manager1", metav1.ManagedFieldsOperationUpdate, 0), `{"f:data":{"f:a":{}}}`), withFields(entry("manager2", metav1.ManagedFieldsOperationUpdate, 0), `{"f:data":{"f:b":{},"f:c":{}}}`)},
+			"manager2",
+		),
+		Entry("nothing is changed within the same second with several Update managers",
+			[]metav1.ManagedFieldsEntry{entry("manager1", metav1.ManagedFieldsOperationUpdate, 0), entry("manager2", metav1.ManagedFieldsOperationUpdate, 0)},
+			[]metav1.ManagedFieldsEntry{entry("manager1", metav1.ManagedFieldsOperationUpdate, 0), entry("manager2", metav1.ManagedFieldsOperationUpdate, 0)},
+			"",
+		),
+		Entry("several managers have the same latest timestamp",
+			[]metav1.ManagedFieldsEntry{entry("manager1", metav1.ManagedFieldsOperationUpdate, 0), entry("manager2", metav1.ManagedFieldsOperationUpdate, 0)},
+			[]metav1.ManagedFieldsEntry{entry("manager1", metav1.ManagedFieldsOperationUpdate, 1), entry("manager2", metav1.ManagedFieldsOperationUpdate, 1)},
+			"",
+		),
+		Entry("creation",
+			nil,
+			[]metav1.ManagedFieldsEntry{entry("manager1", metav1.ManagedFieldsOperationUpdate, 0)},
+			"manager1",
+		),
+	)
+
+	Context("resource is deleted and recreated", func() {
+		var watcher *BlameWatcher
+		object := func(uid types.UID, resourceVersion string, fields ...metav1.ManagedFieldsEntry) *metav1.PartialObjectMetadata {
+			return &metav1.PartialObjectMetadata{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:         "default",
+					Name:              "test",
+					UID:               uid,
+					ResourceVersion:   resourceVersion,
+					CreationTimestamp: *fields[0].Time,
+					ManagedFields:     fields,
+				},
+			}
+		}
+
+		BeforeEach(func() {
+			watcher = NewBlameWatcher(ctrl.Log.WithName("blame-test"), nil, metav1.SchemeGroupVersion.WithKind("ConfigMap"), "default", "test")
+			watcher.startTime = base
+		})
+
+		It("should keep the history of incarnations", func() {
+			first := object("uid-1", "1", entry("manager1", metav1.ManagedFieldsOperationUpdate, 1))
+			watcher.collect(nil, first, false)
+			updated := object("uid-1", "2", entry("manager1", metav1.ManagedFieldsOperationUpdate, 1), entry("manager2", metav1.ManagedFieldsOperationUpdate, 2))
+			watcher.collect(first, updated, false)
+			watcher.delete(updated, base.Add(3*time.Second))
+
+			// other resources should be ignored
+			other := object("uid-x", "4", entry("manager3", metav1.ManagedFieldsOperationUpdate, 3))
+			other.Namespace = "user-ns"
+			watcher.collect(nil, other, false)
+
+			second := object("uid-2", "5", entry("helm", metav1.ManagedFieldsOperationUpdate, 4))
+			watcher.collect(nil, second, false)
+
+			statistics := watcher.Statistics()
+			Expect(statistics.UID).Should(Equal(types.UID("uid-2")))
+			Expect(statistics.CreatedBy).Should(Equal("helm"))
+			Expect(statistics.DeletedAt).Should(BeNil())
+			Expect(statistics.Managers).Should(BeEmpty())
+			Expect(statistics.History).Should(ConsistOf(MatchFields(IgnoreExtras, Fields{
+				"UID":       Equal(types.UID("uid-1")),
+				"CreatedBy": Equal("manager1"),
+				"DeletedAt": Not(BeNil()),
+				"Managers": MatchAllKeys(Keys{
+					"manager2": PointTo(MatchAllFields(Fields{
+						"UpdateCount": Equal(1),
+						"Users":       BeNil(),
+						"UserAgents":  BeNil(),
+					})),
+				}),
+			})))
+		})
+
+		It("should attribute the writes within the same second", func() {
+			first := object("uid-1", "1", entry("helm", metav1.ManagedFieldsOperationUpdate, 0))
+			watcher.collect(nil, first, true)
+			// A controller in a hot loop writes twice within the same second
+			updated := object("uid-1", "2", entry("helm", metav1.ManagedFieldsOperationUpdate, 0), withFields(entry("controller", metav1.ManagedFieldsOperationUpdate, 1), `{"f:data":{"f:a":{}}}`))
+			watcher.collect(first, updated, false)
+			again := object("uid-1", "3", entry("helm", metav1.ManagedFieldsOperationUpdate, 0), withFields(entry("controller", metav1.ManagedFieldsOperationUpdate, 1), `{"f:data":{"f:a":{},"f:b":{}}}`))
+			watcher.collect(updated, again, false)
+
+			statistics := watcher.Statistics()
+			Expect(statistics.Unattributed).Should(BeZero())
+			Expect(statistics.Managers).Should(MatchAllKeys(Keys{
+				"controller": PointTo(MatchFields(IgnoreExtras, Fields{"UpdateCount": Equal(2)})),
+			}))
+		})
+
+		It("should not know who created the resource listed at the start of watching", func() {
+			// The creation timestamp of kube-apiserver may be ahead of the local clock
+			watcher.collect(nil, object("uid-1", "1", entry("manager1", metav1.ManagedFieldsOperationUpdate, 10)), true)
+
+			statistics := watcher.Statistics()
+			Expect(statistics.UID).Should(Equal(types.UID("uid-1")))
+			Expect(statistics.CreatedBy).Should(BeEmpty())
+			Expect(statistics.LatestUpdate).Should(Equal(base))
+			Expect(statistics.History).Should(BeEmpty())
+		})
+
+		It("should know who created the resource after the start of watching", func() {
+			// The creation timestamp of kube-apiserver may be behind the local clock
+			watcher.startTime = base.Add(10 * time.Second)
+			watcher.collect(nil, object("uid-1", "1", entry("manager1", metav1.ManagedFieldsOperationUpdate, 1)), false)
+
+			statistics := watcher.Statistics()
+			Expect(statistics.UID).Should(Equal(types.UID("uid-1")))
+			Expect(statistics.CreatedBy).Should(Equal("manager1"))
+		})
+	})
+})
@@ -0,0 +1,93 @@
+package watch
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	resourceEventsCountDesc = prometheus.NewDesc(
+		"kubbernecker_resource_events_total",
+		"Total number of events for Kubernetes resources",
+		[]string{"group", "version", "kind", "namespace", "event_type", "resource_name"}, nil)
+	resourceUpdateIntervalDesc = prometheus.NewDesc(
+		"kubbernecker_resource_update_interval_seconds",
+		"Histogram of the seconds between consecutive updates of each Kubernetes resource",
+		[]string{"group", "version", "kind", "namespace"}, nil)
+	watcherSyncedDesc = prometheus.NewDesc(
+		"kubbernecker_watcher_synced",
+		"Whether the informer of the watcher has synced. The value is 1 if synced, 0 otherwise",
+		[]string{"group", "version", "kind"}, nil)
+	watcherRelistsDesc = prometheus.NewDesc(
+		"kubbernecker_watcher_relists_total",
+		"Total number of times the informer listed the resources again after the initial list",
+		[]string{"group", "version", "kind"}, nil)
+	watcherWatchErrorsDesc = prometheus.NewDesc(
+		"kubbernecker_watcher_watch_errors_total",
+		"Total number of errors of the list and watch requests of the informer",
+		[]string{"group", "version", "kind"}, nil)
+)
+
+// Collector is a prometheus.Collector exposing the statistics of watchers.
+type Collector struct {
+	watchers func() []*Watcher
+}
+
+// NewCollector creates a Collector. watchers is called on every scrape to get the watchers to expose.
+func NewCollector(watchers func() []*Watcher) *Collector {
+	return &Collector{
+		watchers: watchers,
+	}
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- resourceEventsCountDesc
+	ch <- resourceUpdateIntervalDesc
+	ch <- watcherSyncedDesc
+	ch <- watcherRelistsDesc
+	ch <- watcherWatchErrorsDesc
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	watchers := c.watchers()
+
+	for _, watcher := range watchers {
+		gvk := watcher.GroupVersionKind()
+		synced := 0.0
+		if watcher.HasSynced() {
+			synced = 1.0
+		}
+		ch <- prometheus.MustNewConstMetric(watcherSyncedDesc, prometheus.GaugeValue, synced, gvk.Group, gvk.Version, gvk.Kind)
+
+		relists, watchErrors := watcher.ListWatchErrors()
+		ch <- prometheus.MustNewConstMetric(watcherRelistsDesc, prometheus.CounterValue, float64(relists), gvk.Group, gvk.Version, gvk.Kind)
+		ch <- prometheus.MustNewConstMetric(watcherWatchErrorsDesc, prometheus.CounterValue, float64(watchErrors), gvk.Group, gvk.Version, gvk.Kind)
+
+		// The metrics are made while ranging over the statistics to avoid copying them,
+		// and sent after that not to hold the lock of the watcher while the channel blocks.
+		var metrics []prometheus.Metric
+		watcher.Range(func(ns, res string, resStatistics *ResourceStatistics) bool {
+			metrics = append(metrics,
+				prometheus.MustNewConstMetric(resourceEventsCountDesc, prometheus.CounterValue, float64(resStatistics.UpdateCount), gvk.Group, gvk.Version, gvk.Kind, ns, "update", res),
+				prometheus.MustNewConstMetric(resourceEventsCountDesc, prometheus.CounterValue, float64(resStatistics.AddCount), gvk.Group, gvk.Version, gvk.Kind, ns, "add", res),
+				prometheus.MustNewConstMetric(resourceEventsCountDesc, prometheus.CounterValue, float64(resStatistics.DeleteCount), gvk.Group, gvk.Version, gvk.Kind, ns, "delete", res),
+			)
+			return true
+		})
+		for _, metric := range metrics {
+			ch <- metric
+		}
+	}
+
+	for _, watcher := range watchers {
+		gvk := watcher.GroupVersionKind()
+		for ns, histogram := range watcher.UpdateIntervals() {
+			ch <- prometheus.MustNewConstHistogram(
+				resourceUpdateIntervalDesc,
+				histogram.Count,
+				histogram.Sum,
+				histogram.Buckets,
+				gvk.Group, gvk.Version, gvk.Kind, ns,
+			)
+		}
+	}
+}
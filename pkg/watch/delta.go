@@ -0,0 +1,156 @@
+package watch
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Delta is a change of the statistics of a resource.
+type Delta struct {
+	Time      time.Time
+	Namespace string
+	Name      string
+	// Type is the type of the latest event, "add", "update" or "delete".
+	Type string
+	// Manager is the manager that performed the latest write if known.
+	Manager string
+	// Events is the number of events merged into the delta.
+	Events int
+	// Statistics is the statistics of the resource after the events.
+	Statistics ResourceStatistics
+}
+
+// AddObserver registers fn to be called with the delta of every counted event, and returns a function to remove it.
+// fn is called in the event handler of the source, so it should not block.
+func (w *Watcher) AddObserver(fn func(Delta)) (remove func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	id := w.nextID
+	w.nextID++
+	w.observers[id] = fn
+	w.publishObservers()
+	return func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		delete(w.observers, id)
+		w.publishObservers()
+	}
+}
+
+// publishObservers replaces the list of the observers read by notify. The lock has to be held.
+func (w *Watcher) publishObservers() {
+	observers := make([]func(Delta), 0, len(w.observers))
+	for _, fn := range w.observers {
+		observers = append(observers, fn)
+	}
+	w.observerList.Store(&observers)
+}
+
+func (w *Watcher) notify(delta Delta) {
+	observers := w.observerList.Load()
+	if observers == nil {
+		return
+	}
+	for _, fn := range *observers {
+		fn(delta)
+	}
+}
+
+// Subscribe returns a channel receiving the deltas of the statistics in batches until ctx is done.
+// If interval is 0, the deltas are sent as soon as the receiver is ready, otherwise they are sent every interval.
+// While the receiver is not ready, the deltas of the same resource are merged, so the events are never dropped
+// and the pending deltas are bounded by the number of resources.
+func (w *Watcher) Subscribe(ctx context.Context, interval time.Duration) <-chan []Delta {
+	b := &deltaBatcher{
+		pending: make(map[string]*Delta),
+		notify:  make(chan struct{}, 1),
+		out:     make(chan []Delta),
+	}
+	remove := w.AddObserver(b.add)
+	go func() {
+		defer close(b.out)
+		defer remove()
+		b.run(ctx, interval)
+	}()
+	return b.out
+}
+
+type deltaBatcher struct {
+	mu      sync.Mutex
+	pending map[string]*Delta
+	order   []string
+	notify  chan struct{}
+	out     chan []Delta
+}
+
+func (b *deltaBatcher) add(delta Delta) {
+	b.mu.Lock()
+	key := delta.Namespace + "/" + delta.Name
+	if pending, ok := b.pending[key]; ok {
+		delta.Events += pending.Events
+		*pending = delta
+	} else {
+		b.pending[key] = &delta
+		b.order = append(b.order, key)
+	}
+	b.mu.Unlock()
+
+	select {
+	case b.notify <- struct{}{}:
+	default:
+	}
+}
+
+// take returns the pending deltas in the order of their first events.
+func (b *deltaBatcher) take() []Delta {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.order) == 0 {
+		return nil
+	}
+	deltas := make([]Delta, 0, len(b.order))
+	for _, key := range b.order {
+		deltas = append(deltas, *b.pending[key])
+	}
+	b.pending = make(map[string]*Delta)
+	b.order = nil
+	return deltas
+}
+
+func (b *deltaBatcher) run(ctx context.Context, interval time.Duration) {
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		if interval > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-tick:
+			}
+		} else {
+			select {
+			case <-ctx.Done():
+				return
+			case <-b.notify:
+			}
+		}
+
+		deltas := b.take()
+		if len(deltas) == 0 {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case b.out <- deltas:
+		}
+	}
+}
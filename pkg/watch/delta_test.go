@@ -0,0 +1,187 @@
+package watch
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var _ = Describe("Test deltas", func() {
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	base := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+	newMeta := func(name, resourceVersion, manager string) *metav1.PartialObjectMetadata {
+		rv, _ := strconv.Atoi(resourceVersion)
+		return &metav1.PartialObjectMetadata{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:       "default",
+				Name:            name,
+				UID:             types.UID("uid-" + name),
+				ResourceVersion: resourceVersion,
+				ManagedFields: []metav1.ManagedFieldsEntry{
+					{Manager: manager, Operation: metav1.ManagedFieldsOperationUpdate, Time: &metav1.Time{Time: base.Add(time.Duration(rv) * time.Second)}},
+				},
+			},
+		}
+	}
+	startWatcher := func(source *FakeSource) *Watcher {
+		watcher := NewWatcherWithSource(ctrl.Log.WithName("delta-test"), source, "", gvk, labels.Everything(), false)
+		ctx, cancel := context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+		Expect(watcher.Start(ctx)).Should(Succeed())
+		return watcher
+	}
+
+	It("should notify the observers of every event", func() {
+		source := NewFakeSource(newMeta("existing", "1", "helm"))
+		watcher := startWatcher(source)
+
+		var deltas []Delta
+		remove := watcher.AddObserver(func(d Delta) {
+			deltas = append(deltas, d)
+		})
+		source.Add(newMeta("test", "2", "helm"))
+		source.Update(newMeta("test", "3", "my-operator"))
+		remove()
+		source.Delete(newMeta("test", "3", "my-operator"))
+
+		Expect(deltas).Should(HaveExactElements(
+			MatchFields(IgnoreExtras, Fields{
+				"Name":       Equal("test"),
+				"Type":       Equal("add"),
+				"Events":     Equal(1),
+				"Statistics": MatchFields(IgnoreExtras, Fields{"AddCount": Equal(1), "UpdateCount": Equal(0)}),
+			}),
+			MatchFields(IgnoreExtras, Fields{
+				"Name":       Equal("test"),
+				"Type":       Equal("update"),
+				"Manager":    Equal("my-operator"),
+				"Events":     Equal(1),
+				"Statistics": MatchFields(IgnoreExtras, Fields{"AddCount": Equal(1), "UpdateCount": Equal(1)}),
+			}),
+		))
+	})
+
+	It("should merge the deltas of the same resource in a batch", func() {
+		source := NewFakeSource()
+		watcher := startWatcher(source)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		deltas := watcher.Subscribe(ctx, 0)
+		// The events are merged while the receiver is not ready
+		source.Add(newMeta("a", "1", "helm"))
+		source.Add(newMeta("b", "2", "helm"))
+		source.Update(newMeta("a", "3", "my-operator"))
+		source.Update(newMeta("a", "4", "my-operator"))
+
+		var received []Delta
+		Eventually(func() int {
+			select {
+			case batch := <-deltas:
+				received = append(received, batch...)
+			default:
+			}
+			events := 0
+			for _, d := range received {
+				events += d.Events
+			}
+			return events
+		}).Should(Equal(4))
+		latest := make(map[string]Delta)
+		for _, d := range received {
+			latest[d.Name] = d
+		}
+		Expect(latest["a"].Statistics.UpdateCount).Should(Equal(2))
+		Expect(latest["b"].Statistics.AddCount).Should(Equal(1))
+
+		cancel()
+		Eventually(deltas).Should(BeClosed())
+		// The observer is removed, so the event is not delivered to the closed channel
+		source.Update(newMeta("a", "5", "my-operator"))
+	})
+
+	It("should merge the deltas until the interval", func() {
+		source := NewFakeSource()
+		watcher := startWatcher(source)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+		deltas := watcher.Subscribe(ctx, 100*time.Millisecond)
+		source.Add(newMeta("a", "1", "helm"))
+		source.Add(newMeta("b", "2", "helm"))
+		source.Update(newMeta("a", "3", "my-operator"))
+
+		var batch []Delta
+		Eventually(deltas).Should(Receive(&batch))
+		Expect(batch).Should(HaveExactElements(
+			MatchFields(IgnoreExtras, Fields{
+				"Name":       Equal("a"),
+				"Type":       Equal("update"),
+				"Manager":    Equal("my-operator"),
+				"Events":     Equal(2),
+				"Statistics": MatchFields(IgnoreExtras, Fields{"AddCount": Equal(1), "UpdateCount": Equal(1)}),
+			}),
+			MatchFields(IgnoreExtras, Fields{
+				"Name":   Equal("b"),
+				"Type":   Equal("add"),
+				"Events": Equal(1),
+			}),
+		))
+	})
+
+	It("should read the statistics of a single resource", func() {
+		source := NewFakeSource()
+		watcher := startWatcher(source)
+		source.Add(newMeta("a", "1", "helm"))
+		source.Update(newMeta("a", "2", "helm"))
+		source.Add(newMeta("b", "3", "helm"))
+		source.Relist()
+
+		statistics, ok := watcher.Resource("default", "a")
+		Expect(ok).Should(BeTrue())
+		Expect(statistics.AddCount).Should(Equal(1))
+		Expect(statistics.UpdateCount).Should(Equal(1))
+		_, ok = watcher.Resource("default", "c")
+		Expect(ok).Should(BeFalse())
+		_, ok = watcher.Resource("other", "a")
+		Expect(ok).Should(BeFalse())
+
+		names := make(map[string]int)
+		watcher.Range(func(ns, name string, statistics *ResourceStatistics) bool {
+			names[ns+"/"+name] = statistics.AddCount + statistics.UpdateCount
+			return true
+		})
+		Expect(names).Should(Equal(map[string]int{"default/a": 2, "default/b": 1}))
+
+		relists, watchErrors := watcher.ListWatchErrors()
+		Expect(relists).Should(Equal(1))
+		Expect(watchErrors).Should(Equal(0))
+
+		expected := `
+# HELP kubbernecker_resource_events_total Total number of events for Kubernetes resources
+# TYPE kubbernecker_resource_events_total counter
+kubbernecker_resource_events_total{event_type="add",group="",kind="ConfigMap",namespace="default",resource_name="a",version="v1"} 1
+kubbernecker_resource_events_total{event_type="add",group="",kind="ConfigMap",namespace="default",resource_name="b",version="v1"} 1
+kubbernecker_resource_events_total{event_type="delete",group="",kind="ConfigMap",namespace="default",resource_name="a",version="v1"} 0
+kubbernecker_resource_events_total{event_type="delete",group="",kind="ConfigMap",namespace="default",resource_name="b",version="v1"} 0
+kubbernecker_resource_events_total{event_type="update",group="",kind="ConfigMap",namespace="default",resource_name="a",version="v1"} 1
+kubbernecker_resource_events_total{event_type="update",group="",kind="ConfigMap",namespace="default",resource_name="b",version="v1"} 0
+# HELP kubbernecker_watcher_relists_total Total number of times the informer listed the resources again after the initial list
+# TYPE kubbernecker_watcher_relists_total counter
+kubbernecker_watcher_relists_total{group="",kind="ConfigMap",version="v1"} 1
+`
+		collector := NewCollector(func() []*Watcher { return []*Watcher{watcher} })
+		err := testutil.CollectAndCompare(collector, strings.NewReader(expected), "kubbernecker_resource_events_total", "kubbernecker_watcher_relists_total")
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+})
@@ -0,0 +1,93 @@
+//go:build envtest
+
+package watch
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/zoetrope/kubbernecker/pkg/client"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+)
+
+// The specs with the envtest label run against kube-apiserver of envtest.
+// They are only built with the envtest build tag, so that the other specs can run without the binaries of envtest.
+
+var cfg *rest.Config
+var kubeClient *client.KubeClient
+var testEnv *envtest.Environment
+var scheme = runtime.NewScheme()
+var cancelCluster context.CancelFunc
+
+func startTestEnv() {
+	By("bootstrapping test environment")
+	testEnv = &envtest.Environment{}
+
+	var err error
+	// cfg is defined in this file globally.
+	cfg, err = testEnv.Start()
+	Expect(err).NotTo(HaveOccurred())
+	Expect(cfg).NotTo(BeNil())
+	err = clientgoscheme.AddToScheme(scheme)
+	Expect(err).NotTo(HaveOccurred())
+
+	//+kubebuilder:scaffold:scheme
+
+	kubeClient, err = client.MakeKubeClientFromRestConfig(cfg, "")
+	Expect(err).NotTo(HaveOccurred())
+	Expect(kubeClient).NotTo(BeNil())
+
+	var ctx context.Context
+	ctx, cancelCluster = context.WithCancel(context.Background())
+	go kubeClient.Cluster.Start(ctx)
+
+	cli := kubeClient.Cluster.GetClient()
+	// wait for creating default namespace
+	Eventually(func(g Gomega) {
+		ns := &corev1.Namespace{}
+		err = cli.Get(ctx, ctrlclient.ObjectKey{Name: "default"}, ns)
+		g.Expect(err).ShouldNot(HaveOccurred())
+	}).Should(Succeed())
+
+	ns1 := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "admin-ns",
+			Labels: map[string]string{
+				"role": "admin",
+			},
+		},
+	}
+	err = cli.Create(ctx, ns1)
+	Expect(err).ShouldNot(HaveOccurred())
+
+	ns2 := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "user-ns",
+			Labels: map[string]string{
+				"role": "user",
+			},
+		},
+	}
+	err = cli.Create(ctx, ns2)
+	Expect(err).ShouldNot(HaveOccurred())
+}
+
+func stopTestEnv() {
+	By("tearing down the test environment")
+	if cancelCluster != nil {
+		cancelCluster()
+	}
+	if cfg == nil {
+		// The environment failed to start
+		return
+	}
+	err := testEnv.Stop()
+	Expect(err).NotTo(HaveOccurred())
+}
@@ -0,0 +1,26 @@
+package watch
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Event is an event of a resource counted by a Watcher.
+type Event struct {
+	Time             time.Time
+	Cluster          string
+	GroupVersionKind schema.GroupVersionKind
+	Namespace        string
+	Name             string
+	// Type is "add", "update" or "delete".
+	Type string
+	// Manager is the manager that performed the write. It is empty if the write cannot be attributed to a single manager.
+	Manager string
+}
+
+// EventSink receives the events counted by a Watcher. Record is called in the event handler of the informer,
+// so it should not block.
+type EventSink interface {
+	Record(event Event)
+}
@@ -0,0 +1,105 @@
+package watch
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// FakeSource is an EventSource whose events are emitted by hand. It is intended for testing.
+// The events are delivered synchronously in the calling goroutine, so they have to be emitted after Start.
+type FakeSource struct {
+	mu      sync.Mutex
+	handler EventHandler
+	objects map[string]*metav1.PartialObjectMetadata
+	initial []*metav1.PartialObjectMetadata
+	// Clock returns the time of the emitted events. The default is time.Now.
+	Clock func() time.Time
+}
+
+// NewFakeSource creates a FakeSource. The given objects are delivered as the initial list on Start.
+func NewFakeSource(initial ...*metav1.PartialObjectMetadata) *FakeSource {
+	return &FakeSource{
+		objects: make(map[string]*metav1.PartialObjectMetadata),
+		initial: initial,
+		Clock:   time.Now,
+	}
+}
+
+func (s *FakeSource) Start(ctx context.Context, handler EventHandler) error {
+	s.mu.Lock()
+	s.handler = handler
+	initial := s.initial
+	s.initial = nil
+	s.mu.Unlock()
+
+	for _, obj := range initial {
+		s.emit("add", obj, true)
+	}
+	return nil
+}
+
+// HasSynced returns true after Start.
+func (s *FakeSource) HasSynced() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.handler != nil
+}
+
+func (s *FakeSource) GetObject(namespace, name string) (*metav1.PartialObjectMetadata, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, ok := s.objects[namespace+"/"+name]
+	return obj, ok
+}
+
+// Add emits an add event of the object.
+func (s *FakeSource) Add(obj *metav1.PartialObjectMetadata) {
+	s.emit("add", obj, false)
+}
+
+// Update emits an update event of the object with the previously emitted state.
+func (s *FakeSource) Update(obj *metav1.PartialObjectMetadata) {
+	s.emit("update", obj, false)
+}
+
+// Delete emits a delete event of the object.
+func (s *FakeSource) Delete(obj *metav1.PartialObjectMetadata) {
+	s.emit("delete", obj, false)
+}
+
+// Relist notifies the handler of a relist.
+func (s *FakeSource) Relist() {
+	s.mu.Lock()
+	handler := s.handler
+	s.mu.Unlock()
+	handler.OnRelist()
+}
+
+// WatchError notifies the handler of a watch error.
+func (s *FakeSource) WatchError(err error) {
+	s.mu.Lock()
+	handler := s.handler
+	s.mu.Unlock()
+	handler.OnWatchError(err)
+}
+
+func (s *FakeSource) emit(eventType string, obj *metav1.PartialObjectMetadata, initial bool) {
+	s.mu.Lock()
+	handler := s.handler
+	key := obj.Namespace + "/" + obj.Name
+	event := &SourceEvent{Type: eventType, Object: obj, Initial: initial, Time: s.Clock()}
+	if eventType == "update" {
+		event.OldObject = s.objects[key]
+	}
+	if eventType == "delete" {
+		delete(s.objects, key)
+	} else {
+		s.objects[key] = obj
+	}
+	s.mu.Unlock()
+
+	handler.OnEvent(event)
+}
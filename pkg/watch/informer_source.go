@@ -0,0 +1,287 @@
+package watch
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/zoetrope/kubbernecker/pkg/client"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	k8swatch "k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/rest"
+	toolscache "k8s.io/client-go/tools/cache"
+	ctrlcache "sigs.k8s.io/controller-runtime/pkg/cache"
+)
+
+// InformerSource delivers the events of an informer watching the metadata of the resources.
+// It reports relists and watch errors, and the objects in the initial list.
+type InformerSource struct {
+	kube     *client.KubeClient
+	gvk      schema.GroupVersionKind
+	informer toolscache.SharedIndexInformer
+	handler  EventHandler
+
+	mu    sync.Mutex
+	lists int
+	// initialObjects holds the resourceVersions of the objects in the initial list
+	initialObjects map[types.UID]string
+}
+
+func NewInformerSource(kube *client.KubeClient, gvk schema.GroupVersionKind) *InformerSource {
+	return &InformerSource{
+		kube:           kube,
+		gvk:            gvk,
+		initialObjects: make(map[types.UID]string),
+	}
+}
+
+func (s *InformerSource) Start(ctx context.Context, handler EventHandler) error {
+	s.handler = handler
+	lw, err := s.listWatch(ctx)
+	if err != nil {
+		return err
+	}
+	// The informer is not shared with the cache of controller-runtime,
+	// because the watch error handler has to be set before starting the informer.
+	informer := toolscache.NewSharedIndexInformer(lw, &metav1.PartialObjectMetadata{}, 0, toolscache.Indexers{})
+	err = informer.SetWatchErrorHandler(s.handleWatchError)
+	if err != nil {
+		return err
+	}
+	s.informer = informer
+
+	_, err = informer.AddEventHandler(s.eventHandler())
+	if err != nil {
+		return err
+	}
+	go informer.Run(ctx.Done())
+	return nil
+}
+
+// HasSynced returns true if the informer has synced the initial list of the resources.
+func (s *InformerSource) HasSynced() bool {
+	return s.informer != nil && s.informer.HasSynced()
+}
+
+// GetObject returns the metadata of the resource in the informer's store.
+func (s *InformerSource) GetObject(namespace, name string) (*metav1.PartialObjectMetadata, bool) {
+	if s.informer == nil {
+		return nil, false
+	}
+	key := name
+	if namespace != "" {
+		key = namespace + "/" + name
+	}
+	obj, exists, err := s.informer.GetStore().GetByKey(key)
+	if err != nil || !exists {
+		return nil, false
+	}
+	meta, ok := obj.(*metav1.PartialObjectMetadata)
+	return meta, ok
+}
+
+// eventHandler converts the notifications of the informer into the events of the source.
+func (s *InformerSource) eventHandler() toolscache.ResourceEventHandlerFuncs {
+	return toolscache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if meta, ok := toPartialObjectMetadata(obj); ok {
+				s.handler.OnEvent(&SourceEvent{Type: "add", Object: meta, Initial: s.isInitialObject(meta), Time: time.Now()})
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			if isResync(oldObj, newObj) {
+				// Objects listed again by relist are notified as updates even if they are not changed
+				return
+			}
+			if meta, ok := toPartialObjectMetadata(newObj); ok {
+				oldMeta, _ := toPartialObjectMetadata(oldObj)
+				s.handler.OnEvent(&SourceEvent{Type: "update", Object: meta, OldObject: oldMeta, Time: time.Now()})
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if meta, ok := toPartialObjectMetadata(obj); ok {
+				s.handler.OnEvent(&SourceEvent{Type: "delete", Object: meta, Time: time.Now()})
+			}
+		},
+	}
+}
+
+// isInitialObject returns true if the object is notified from the initial list rather than created after that.
+func (s *InformerSource) isInitialObject(meta *metav1.PartialObjectMetadata) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resourceVersion, ok := s.initialObjects[meta.UID]
+	if !ok {
+		return false
+	}
+	delete(s.initialObjects, meta.UID)
+	return resourceVersion == meta.ResourceVersion
+}
+
+// listWatch creates a ListWatch for the metadata of the resources, which reports relists to the handler.
+// The requests in flight are canceled when ctx is done.
+func (s *InformerSource) listWatch(ctx context.Context) (*toolscache.ListWatch, error) {
+	mapping, err := s.kube.Cluster.GetRESTMapper().RESTMapping(s.gvk.GroupKind(), s.gvk.Version)
+	if err != nil {
+		return nil, err
+	}
+	cfg := rest.CopyConfig(s.kube.Cluster.GetConfig())
+	cfg.NegotiatedSerializer = nil
+	client, err := metadata.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var resource metadata.ResourceInterface = client.Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace && s.kube.WatchNamespace != "" {
+		resource = client.Resource(mapping.Resource).Namespace(s.kube.WatchNamespace)
+	}
+
+	return &toolscache.ListWatch{
+		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			list, err := resource.List(ctx, opts)
+			if err != nil {
+				return nil, err
+			}
+			s.mu.Lock()
+			if opts.Continue == "" {
+				s.lists++
+			}
+			lists := s.lists
+			for i := range list.Items {
+				list.Items[i].SetGroupVersionKind(s.gvk)
+				if lists == 1 {
+					s.initialObjects[list.Items[i].UID] = list.Items[i].ResourceVersion
+				}
+			}
+			s.mu.Unlock()
+			if opts.Continue == "" && lists > 1 {
+				s.handler.OnRelist()
+			}
+			return list, nil
+		},
+		WatchFunc: func(opts metav1.ListOptions) (k8swatch.Interface, error) {
+			opts.Watch = true
+			return resource.Watch(ctx, opts)
+		},
+	}, nil
+}
+
+func (s *InformerSource) handleWatchError(r *toolscache.Reflector, err error) {
+	s.handler.OnWatchError(err)
+	toolscache.DefaultWatchErrorHandler(r, err)
+}
+
+// CacheSource delivers the events of the informer shared in the cache of controller-runtime.
+// It does not report relists and watch errors. The objects in the store of the informer at the start,
+// and the objects notified before the informer has synced are regarded as the initial list.
+type CacheSource struct {
+	cache    ctrlcache.Cache
+	gvk      schema.GroupVersionKind
+	informer ctrlcache.Informer
+
+	mu sync.Mutex
+	// initialObjects holds the resourceVersions of the objects in the store at the start
+	initialObjects map[types.UID]string
+}
+
+func NewCacheSource(cache ctrlcache.Cache, gvk schema.GroupVersionKind) *CacheSource {
+	return &CacheSource{
+		cache:          cache,
+		gvk:            gvk,
+		initialObjects: make(map[types.UID]string),
+	}
+}
+
+func (s *CacheSource) Start(ctx context.Context, handler EventHandler) error {
+	obj := &metav1.PartialObjectMetadata{}
+	obj.SetGroupVersionKind(s.gvk)
+	informer, err := s.cache.GetInformer(ctx, obj)
+	if err != nil {
+		return err
+	}
+	s.informer = informer
+
+	// The informer may have been started by others, and the objects in its store are notified to a new handler
+	if storer, ok := informer.(interface{ GetStore() toolscache.Store }); ok && storer.GetStore() != nil {
+		s.mu.Lock()
+		for _, obj := range storer.GetStore().List() {
+			if meta, ok := toPartialObjectMetadata(obj); ok {
+				s.initialObjects[meta.UID] = meta.ResourceVersion
+			}
+		}
+		s.mu.Unlock()
+	}
+
+	// The events notified after ctx is done are ignored until the handler is removed
+	reg, err := informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if ctx.Err() != nil {
+				return
+			}
+			if meta, ok := toPartialObjectMetadata(obj); ok {
+				handler.OnEvent(&SourceEvent{Type: "add", Object: meta, Initial: s.isInitialObject(meta), Time: time.Now()})
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			if ctx.Err() != nil || isResync(oldObj, newObj) {
+				return
+			}
+			if meta, ok := toPartialObjectMetadata(newObj); ok {
+				oldMeta, _ := toPartialObjectMetadata(oldObj)
+				handler.OnEvent(&SourceEvent{Type: "update", Object: meta, OldObject: oldMeta, Time: time.Now()})
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if ctx.Err() != nil {
+				return
+			}
+			if meta, ok := toPartialObjectMetadata(obj); ok {
+				handler.OnEvent(&SourceEvent{Type: "delete", Object: meta, Time: time.Now()})
+			}
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// The informer is shared with others, so only the handler is removed from it
+	go func() {
+		<-ctx.Done()
+		// It fails only if the registration was not returned by the informer
+		_ = informer.RemoveEventHandler(reg)
+	}()
+	return nil
+}
+
+// HasSynced returns true if the informer has synced the initial list of the resources.
+func (s *CacheSource) HasSynced() bool {
+	return s.informer != nil && s.informer.HasSynced()
+}
+
+func (s *CacheSource) isInitialObject(meta *metav1.PartialObjectMetadata) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resourceVersion, ok := s.initialObjects[meta.UID]
+	if ok {
+		delete(s.initialObjects, meta.UID)
+		return resourceVersion == meta.ResourceVersion
+	}
+	return !s.informer.HasSynced()
+}
+
+// toPartialObjectMetadata returns the metadata of the object notified by an informer.
+func toPartialObjectMetadata(obj interface{}) (*metav1.PartialObjectMetadata, bool) {
+	if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+		// The resource was deleted while the watch was disconnected
+		obj = tombstone.Obj
+	}
+	meta, ok := obj.(*metav1.PartialObjectMetadata)
+	return meta, ok
+}
@@ -0,0 +1,105 @@
+package watch
+
+import (
+	"sort"
+	"time"
+)
+
+// UpdateIntervalBuckets are the upper bounds of the buckets of the update interval histograms in seconds.
+var UpdateIntervalBuckets = []float64{0.1, 0.5, 1, 2, 5, 10, 30, 60, 300, 600, 1800, 3600}
+
+// maxRecentIntervals is the number of recent intervals kept for each object to calculate the median.
+const maxRecentIntervals = 100
+
+// IntervalHistogram is a histogram of the intervals between consecutive updates of objects.
+type IntervalHistogram struct {
+	Count uint64
+	Sum   float64
+	// Buckets holds the cumulative counts for each upper bound in UpdateIntervalBuckets.
+	Buckets map[float64]uint64
+}
+
+func newIntervalHistogram() *IntervalHistogram {
+	buckets := make(map[float64]uint64, len(UpdateIntervalBuckets))
+	for _, bound := range UpdateIntervalBuckets {
+		buckets[bound] = 0
+	}
+	return &IntervalHistogram{Buckets: buckets}
+}
+
+func (h *IntervalHistogram) observe(interval time.Duration) {
+	seconds := interval.Seconds()
+	h.Count++
+	h.Sum += seconds
+	for _, bound := range UpdateIntervalBuckets {
+		if seconds <= bound {
+			h.Buckets[bound]++
+		}
+	}
+}
+
+func (h *IntervalHistogram) DeepCopy() *IntervalHistogram {
+	if h == nil {
+		return nil
+	}
+	out := &IntervalHistogram{
+		Count:   h.Count,
+		Sum:     h.Sum,
+		Buckets: make(map[float64]uint64, len(h.Buckets)),
+	}
+	for bound, count := range h.Buckets {
+		out.Buckets[bound] = count
+	}
+	return out
+}
+
+// merge adds the observations of other to the histogram.
+func (h *IntervalHistogram) merge(other *IntervalHistogram) {
+	h.Count += other.Count
+	h.Sum += other.Sum
+	for bound, count := range other.Buckets {
+		h.Buckets[bound] += count
+	}
+}
+
+// intervalTracker tracks the intervals between consecutive updates of an object.
+type intervalTracker struct {
+	lastUpdate time.Time
+	min        time.Duration
+	recent     []time.Duration
+}
+
+// update records the update at the given time, and returns the interval from the previous update.
+// If this is the first update, false is returned.
+func (t *intervalTracker) update(now time.Time) (time.Duration, bool) {
+	last := t.lastUpdate
+	t.lastUpdate = now
+	if last.IsZero() {
+		return 0, false
+	}
+
+	interval := now.Sub(last)
+	if len(t.recent) == 0 || interval < t.min {
+		t.min = interval
+	}
+	t.recent = append(t.recent, interval)
+	if len(t.recent) > maxRecentIntervals {
+		t.recent = t.recent[1:]
+	}
+	return interval, true
+}
+
+// median returns the median of the recent intervals.
+func (t *intervalTracker) median() time.Duration {
+	if len(t.recent) == 0 {
+		return 0
+	}
+	sorted := make([]time.Duration, len(t.recent))
+	copy(sorted, t.recent)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
@@ -0,0 +1,50 @@
+package watch
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Test update intervals", func() {
+	It("should track the minimum and median intervals", func() {
+		tracker := &intervalTracker{}
+		now := time.Now()
+
+		_, ok := tracker.update(now)
+		Expect(ok).Should(BeFalse())
+		Expect(tracker.median()).Should(BeZero())
+
+		for _, interval := range []time.Duration{5 * time.Second, 1 * time.Second, 3 * time.Second, 10 * time.Second} {
+			now = now.Add(interval)
+			observed, ok := tracker.update(now)
+			Expect(ok).Should(BeTrue())
+			Expect(observed).Should(Equal(interval))
+		}
+		Expect(tracker.min).Should(Equal(1 * time.Second))
+		Expect(tracker.median()).Should(Equal(4 * time.Second))
+
+		for i := 0; i < maxRecentIntervals; i++ {
+			now = now.Add(2 * time.Second)
+			tracker.update(now)
+		}
+		Expect(tracker.recent).Should(HaveLen(maxRecentIntervals))
+		Expect(tracker.min).Should(Equal(1 * time.Second))
+		Expect(tracker.median()).Should(Equal(2 * time.Second))
+	})
+
+	It("should count intervals in cumulative buckets", func() {
+		histogram := newIntervalHistogram()
+		histogram.observe(300 * time.Millisecond)
+		histogram.observe(20 * time.Second)
+
+		Expect(histogram.Count).Should(BeEquivalentTo(2))
+		Expect(histogram.Sum).Should(BeNumerically("~", 20.3))
+		Expect(histogram.Buckets[0.1]).Should(BeEquivalentTo(0))
+		Expect(histogram.Buckets[0.5]).Should(BeEquivalentTo(1))
+		Expect(histogram.Buckets[10]).Should(BeEquivalentTo(1))
+		Expect(histogram.Buckets[30]).Should(BeEquivalentTo(2))
+		Expect(histogram.Buckets[3600]).Should(BeEquivalentTo(2))
+	})
+})
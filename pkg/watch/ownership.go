@@ -0,0 +1,86 @@
+package watch
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/structured-merge-diff/v4/fieldpath"
+)
+
+// FieldOwnership represents the fields owned by a manager, decoded from an entry of managedFields.
+type FieldOwnership struct {
+	Manager     string                            `json:"manager"`
+	Operation   metav1.ManagedFieldsOperationType `json:"operation"`
+	Subresource string                            `json:"subresource,omitempty"`
+	Time        *time.Time                        `json:"time,omitempty"`
+	Fields      []*FieldNode                      `json:"fields,omitempty"`
+}
+
+// FieldNode represents an element of the field paths, such as `.metadata`, `[name="nginx"]` or `[0]`.
+type FieldNode struct {
+	Name     string       `json:"name"`
+	Children []*FieldNode `json:"children,omitempty"`
+}
+
+// Ownership decodes the managedFields of the resource into the field paths owned by each manager.
+func Ownership(meta *metav1.PartialObjectMetadata) ([]FieldOwnership, error) {
+	result := make([]FieldOwnership, 0, len(meta.ManagedFields))
+	for _, field := range meta.ManagedFields {
+		ownership := FieldOwnership{
+			Manager:     field.Manager,
+			Operation:   field.Operation,
+			Subresource: field.Subresource,
+		}
+		if field.Time != nil {
+			t := field.Time.Time
+			ownership.Time = &t
+		}
+		if field.FieldsV1 != nil {
+			set := &fieldpath.Set{}
+			if err := set.FromJSON(bytes.NewReader(field.FieldsV1.Raw)); err != nil {
+				return nil, fmt.Errorf("failed to decode the fields of %s: %w", field.Manager, err)
+			}
+			ownership.Fields = fieldNodes(set)
+		}
+		result = append(result, ownership)
+	}
+	return result, nil
+}
+
+func fieldNodes(set *fieldpath.Set) []*FieldNode {
+	nodes := make(map[string]*FieldNode)
+	var elements []fieldpath.PathElement
+	add := func(pe fieldpath.PathElement) *FieldNode {
+		key := pe.String()
+		if node, ok := nodes[key]; ok {
+			return node
+		}
+		node := &FieldNode{Name: strings.TrimPrefix(key, ".")}
+		nodes[key] = node
+		elements = append(elements, pe)
+		return node
+	}
+
+	set.Members.Iterate(func(pe fieldpath.PathElement) {
+		add(pe)
+	})
+	set.Children.Iterate(func(pe fieldpath.PathElement) {
+		node := add(pe)
+		if child, ok := set.Children.Get(pe); ok {
+			node.Children = fieldNodes(child)
+		}
+	})
+
+	sort.Slice(elements, func(i, j int) bool {
+		return elements[i].Less(elements[j])
+	})
+	result := make([]*FieldNode, 0, len(elements))
+	for _, pe := range elements {
+		result = append(result, nodes[pe.String()])
+	}
+	return result
+}
@@ -0,0 +1,62 @@
+package watch
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Test Ownership", func() {
+	It("should decode the fields owned by each manager", func() {
+		t := metav1.NewTime(time.Date(2023, 2, 17, 22, 25, 20, 0, time.UTC))
+		meta := &metav1.PartialObjectMetadata{
+			ObjectMeta: metav1.ObjectMeta{
+				ManagedFields: []metav1.ManagedFieldsEntry{
+					{
+						Manager:   "manager1",
+						Operation: metav1.ManagedFieldsOperationApply,
+						Time:      &t,
+						FieldsV1: &metav1.FieldsV1{
+							Raw: []byte(`{"f:data":{"f:key1":{},"f:key2":{}},"f:metadata":{"f:labels":{".":{},"f:app":{}}}}`),
+						},
+					},
+					{
+						Manager:     "manager2",
+						Operation:   metav1.ManagedFieldsOperationUpdate,
+						Subresource: "status",
+						FieldsV1: &metav1.FieldsV1{
+							Raw: []byte(`{"f:status":{"f:conditions":{"k:{\"type\":\"Ready\"}":{"f:status":{}}}}}`),
+						},
+					},
+				},
+			},
+		}
+
+		ownerships, err := Ownership(meta)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(ownerships).Should(HaveLen(2))
+
+		Expect(ownerships[0].Manager).Should(Equal("manager1"))
+		Expect(ownerships[0].Operation).Should(Equal(metav1.ManagedFieldsOperationApply))
+		Expect(ownerships[0].Time).Should(PointTo(BeTemporally("==", t.Time)))
+		Expect(ownerships[0].Fields).Should(Equal([]*FieldNode{
+			{Name: "data", Children: []*FieldNode{{Name: "key1"}, {Name: "key2"}}},
+			{Name: "metadata", Children: []*FieldNode{
+				{Name: "labels", Children: []*FieldNode{{Name: "app"}}},
+			}},
+		}))
+
+		Expect(ownerships[1].Subresource).Should(Equal("status"))
+		Expect(ownerships[1].Time).Should(BeNil())
+		Expect(ownerships[1].Fields).Should(Equal([]*FieldNode{
+			{Name: "status", Children: []*FieldNode{
+				{Name: "conditions", Children: []*FieldNode{
+					{Name: `[type="Ready"]`, Children: []*FieldNode{{Name: "status"}}},
+				}},
+			}},
+		}))
+	})
+})
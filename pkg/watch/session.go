@@ -0,0 +1,213 @@
+package watch
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// RecordedEvent is a raw event notified by the informer, recorded in a session.
+type RecordedEvent struct {
+	Time             time.Time               `json:"time"`
+	Cluster          string                  `json:"cluster,omitempty"`
+	GroupVersionKind metav1.GroupVersionKind `json:"gvk"`
+	// Type is "add", "update" or "delete".
+	Type string `json:"type"`
+	// Initial is true if the object is notified from the initial list.
+	Initial bool                          `json:"initial,omitempty"`
+	Object  *metav1.PartialObjectMetadata `json:"object"`
+}
+
+// SessionRecorder writes the raw events of watchers to a stream in JSON Lines format.
+type SessionRecorder struct {
+	mu     sync.Mutex
+	writer *bufio.Writer
+	enc    *json.Encoder
+	err    error
+}
+
+func NewSessionRecorder(w io.Writer) *SessionRecorder {
+	writer := bufio.NewWriter(w)
+	return &SessionRecorder{
+		writer: writer,
+		enc:    json.NewEncoder(writer),
+	}
+}
+
+func (r *SessionRecorder) record(now time.Time, cluster string, gvk schema.GroupVersionKind, event string, initial bool, meta *metav1.PartialObjectMetadata) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return
+	}
+	r.err = r.enc.Encode(&RecordedEvent{
+		Time:    now,
+		Cluster: cluster,
+		GroupVersionKind: metav1.GroupVersionKind{
+			Group:   gvk.Group,
+			Version: gvk.Version,
+			Kind:    gvk.Kind,
+		},
+		Type:    event,
+		Initial: initial,
+		Object:  meta,
+	})
+}
+
+// Flush writes the buffered events, and returns the first error occurred while recording.
+func (r *SessionRecorder) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return r.err
+	}
+	r.err = r.writer.Flush()
+	return r.err
+}
+
+// ReplaySession reads the recorded events from the stream and passes them to fn in order,
+// with the previous state of the object for update events. Updates without any changes are skipped.
+func ReplaySession(r io.Reader, fn func(event *RecordedEvent, oldObj *metav1.PartialObjectMetadata) error) error {
+	objects := make(map[string]*metav1.PartialObjectMetadata)
+	dec := json.NewDecoder(r)
+	for line := 1; ; line++ {
+		event := &RecordedEvent{}
+		err := dec.Decode(event)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid event at line %d: %w", line, err)
+		}
+		if event.Object == nil {
+			return fmt.Errorf("invalid event at line %d: no object", line)
+		}
+
+		key := event.Cluster + "/" + event.GroupVersionKind.String() + "/" + event.Object.Namespace + "/" + event.Object.Name
+		oldObj := objects[key]
+		if event.Type == "delete" {
+			delete(objects, key)
+		} else {
+			objects[key] = event.Object
+		}
+		if event.Type != "update" {
+			oldObj = nil
+		} else if oldObj != nil && isResync(oldObj, event.Object) {
+			continue
+		}
+		if err := fn(event, oldObj); err != nil {
+			return err
+		}
+	}
+}
+
+// SourceEvent converts the recorded event into the event of a source.
+func (e *RecordedEvent) SourceEvent(oldObj *metav1.PartialObjectMetadata) *SourceEvent {
+	return &SourceEvent{
+		Type:      e.Type,
+		Object:    e.Object,
+		OldObject: oldObj,
+		Initial:   e.Initial,
+		Time:      e.Time,
+	}
+}
+
+// NewReplayWatcher creates a Watcher to count the recorded events of the cluster offline.
+// Namespace selectors are not supported, because the labels of namespaces are not recorded.
+func NewReplayWatcher(logger logr.Logger, cluster string, gvk schema.GroupVersionKind, resSelector labels.Selector, includeExisting bool) *Watcher {
+	return NewWatcherWithSource(logger, nil, cluster, gvk, resSelector, includeExisting)
+}
+
+// Replay counts the recorded event in the same way as the events notified by the informer.
+// The events of other clusters or resource types are ignored.
+func (w *Watcher) Replay(event *RecordedEvent, oldObj *metav1.PartialObjectMetadata) {
+	if event.Cluster != w.cluster || event.GroupVersionKind != w.groupVersionKind() {
+		return
+	}
+	w.OnEvent(event.SourceEvent(oldObj))
+}
+
+// NewReplayBlameWatcher creates a BlameWatcher to attribute the recorded events of the cluster offline.
+func NewReplayBlameWatcher(logger logr.Logger, cluster string, gvk schema.GroupVersionKind, namespace string, resource string) *BlameWatcher {
+	return NewBlameWatcherWithSource(logger, nil, cluster, gvk, namespace, resource)
+}
+
+// Replay attributes the recorded event in the same way as the events notified by the informer.
+// The events of other clusters or resource types are ignored.
+func (w *BlameWatcher) Replay(event *RecordedEvent, oldObj *metav1.PartialObjectMetadata) {
+	gvk := metav1.GroupVersionKind{Group: w.gvk.Group, Version: w.gvk.Version, Kind: w.gvk.Kind}
+	w.mu.RLock()
+	cluster := w.statistics.Cluster
+	w.mu.RUnlock()
+	if event.Cluster != cluster || event.GroupVersionKind != gvk {
+		return
+	}
+	w.OnEvent(event.SourceEvent(oldObj))
+}
+
+// SessionSource replays the events of a resource type in a cluster recorded in a session.
+type SessionSource struct {
+	r       io.Reader
+	cluster string
+	gvk     metav1.GroupVersionKind
+
+	synced atomic.Bool
+	err    error
+}
+
+func NewSessionSource(r io.Reader, cluster string, gvk schema.GroupVersionKind) *SessionSource {
+	return &SessionSource{
+		r:       r,
+		cluster: cluster,
+		gvk:     metav1.GroupVersionKind{Group: gvk.Group, Version: gvk.Version, Kind: gvk.Kind},
+	}
+}
+
+// Start replays the session in the background. HasSynced returns true when all the events have been delivered.
+func (s *SessionSource) Start(ctx context.Context, handler EventHandler) error {
+	go func() {
+		s.err = s.Run(ctx, handler)
+		s.synced.Store(true)
+	}()
+	return nil
+}
+
+// Run replays the session until the end of the stream.
+// The events of other clusters or resource types are ignored.
+func (s *SessionSource) Run(ctx context.Context, handler EventHandler) error {
+	return ReplaySession(s.r, func(event *RecordedEvent, oldObj *metav1.PartialObjectMetadata) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if event.Cluster != s.cluster || event.GroupVersionKind != s.gvk {
+			return nil
+		}
+		handler.OnEvent(event.SourceEvent(oldObj))
+		return nil
+	})
+}
+
+func (s *SessionSource) HasSynced() bool {
+	return s.synced.Load()
+}
+
+// Err returns the error occurred while replaying the session in the background. It is only valid after HasSynced returns true.
+func (s *SessionSource) Err() error {
+	if !s.synced.Load() {
+		return nil
+	}
+	return s.err
+}
@@ -0,0 +1,124 @@
+package watch
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var _ = Describe("Test session", func() {
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	now := time.Now()
+	newMeta := func(name, resourceVersion, manager string, t time.Time) *metav1.PartialObjectMetadata {
+		return &metav1.PartialObjectMetadata{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:         "default",
+				Name:              name,
+				UID:               types.UID("uid-" + name),
+				ResourceVersion:   resourceVersion,
+				CreationTimestamp: metav1.NewTime(now.Add(-time.Hour)),
+				Labels:            map[string]string{"app": name},
+				ManagedFields: []metav1.ManagedFieldsEntry{
+					{Manager: manager, Operation: metav1.ManagedFieldsOperationUpdate, Time: &metav1.Time{Time: t}},
+				},
+			},
+		}
+	}
+
+	record := func() *bytes.Buffer {
+		buf := &bytes.Buffer{}
+		recorder := NewSessionRecorder(buf)
+		source := NewFakeSource(newMeta("test", "1", "helm", now.Add(-time.Hour)))
+		watcher := NewWatcherWithSource(ctrl.Log.WithName("session-test"), source, "", gvk, labels.Everything(), false)
+		watcher.SetSessionRecorder(recorder)
+		Expect(watcher.Start(context.Background())).Should(Succeed())
+
+		source.Update(newMeta("test", "2", "controller", now.Add(time.Second)))
+		source.Update(newMeta("test", "3", "kubectl", now.Add(2*time.Second)))
+		other := newMeta("other", "4", "controller", now.Add(3*time.Second))
+		source.Add(other)
+		source.Delete(other)
+		Expect(recorder.Flush()).Should(Succeed())
+		return buf
+	}
+
+	It("should replay the recorded events through the Watcher", func() {
+		session := record()
+		Expect(bytes.Count(session.Bytes(), []byte("\n"))).Should(Equal(5))
+
+		source := NewSessionSource(session, "", gvk)
+		watcher := NewWatcherWithSource(ctrl.Log.WithName("session-test"), source, "", gvk, labels.Everything(), false)
+		Expect(watcher.Start(context.Background())).Should(Succeed())
+		Eventually(watcher.HasSynced).Should(BeTrue())
+		Expect(source.Err()).ShouldNot(HaveOccurred())
+
+		statistics := watcher.Statistics()
+		Expect(statistics.Namespaces["default"].Resources["test"]).Should(PointTo(MatchFields(IgnoreExtras, Fields{
+			"AddCount":    Equal(0),
+			"UpdateCount": Equal(2),
+		})))
+		Expect(statistics.Namespaces["default"].Resources["other"]).Should(PointTo(MatchFields(IgnoreExtras, Fields{
+			"AddCount":    Equal(1),
+			"DeleteCount": Equal(1),
+		})))
+		Expect(watcher.ManagerUpdates("default", "test")).Should(Equal(map[string]int{"controller": 1, "kubectl": 1}))
+	})
+
+	It("should replay the recorded events with a different filter", func() {
+		watcher := NewWatcherWithSource(ctrl.Log.WithName("session-test"), nil, "", gvk, labels.SelectorFromSet(labels.Set{"app": "other"}), true)
+		Expect(NewSessionSource(record(), "", gvk).Run(context.Background(), watcher)).Should(Succeed())
+
+		statistics := watcher.Statistics()
+		Expect(statistics.Namespaces["default"].Resources).Should(HaveLen(1))
+		Expect(statistics.Namespaces["default"].Resources).Should(HaveKey("other"))
+	})
+
+	It("should replay the recorded events of the cluster with Replay", func() {
+		watcher := NewReplayWatcher(ctrl.Log.WithName("session-test"), "", gvk, labels.Everything(), false)
+		other := NewReplayWatcher(ctrl.Log.WithName("session-test"), "other", gvk, labels.Everything(), false)
+		Expect(ReplaySession(record(), func(event *RecordedEvent, oldObj *metav1.PartialObjectMetadata) error {
+			watcher.Replay(event, oldObj)
+			other.Replay(event, oldObj)
+			return nil
+		})).Should(Succeed())
+
+		statistics := watcher.Statistics()
+		Expect(statistics.Namespaces["default"].Resources["test"]).Should(PointTo(MatchFields(IgnoreExtras, Fields{
+			"AddCount":    Equal(0),
+			"UpdateCount": Equal(2),
+		})))
+		Expect(watcher.ManagerUpdates("default", "test")).Should(Equal(map[string]int{"controller": 1, "kubectl": 1}))
+		// The events of other clusters are ignored
+		Expect(other.Statistics().Namespaces).Should(BeEmpty())
+	})
+
+	It("should replay the recorded events through the BlameWatcher", func() {
+		watcher := NewReplayBlameWatcher(ctrl.Log.WithName("session-test"), "", gvk, "default", "test")
+		Expect(ReplaySession(record(), func(event *RecordedEvent, oldObj *metav1.PartialObjectMetadata) error {
+			watcher.Replay(event, oldObj)
+			return nil
+		})).Should(Succeed())
+
+		statistics := watcher.Statistics()
+		Expect(statistics.Managers).Should(HaveLen(2))
+		Expect(statistics.Managers["controller"].UpdateCount).Should(Equal(1))
+		Expect(statistics.Managers["kubectl"].UpdateCount).Should(Equal(1))
+		Expect(statistics.CreatedBy).Should(BeEmpty())
+	})
+
+	It("should reject broken sessions", func() {
+		err := ReplaySession(bytes.NewBufferString("{\"type\":\"add\"}\n"), func(*RecordedEvent, *metav1.PartialObjectMetadata) error {
+			return nil
+		})
+		Expect(err).Should(HaveOccurred())
+	})
+})
@@ -0,0 +1,118 @@
+package watch
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Snapshot is the state of a Watcher that can be persisted and restored across restarts.
+type Snapshot struct {
+	Statistics *Statistics `json:"statistics"`
+	// Managers holds the number of updates for each manager keyed by "namespace/name" of the resources.
+	Managers map[string]map[string]int `json:"managers,omitempty"`
+	// Intervals holds the histograms of the update intervals for each namespace.
+	Intervals map[string]*IntervalHistogram `json:"intervals,omitempty"`
+}
+
+// Snapshot returns a copy of the current state of the watcher.
+func (w *Watcher) Snapshot() *Snapshot {
+	snapshot := &Snapshot{
+		Statistics: w.Statistics(),
+		Managers:   make(map[string]map[string]int),
+		Intervals:  w.store.intervals(),
+	}
+	w.store.forEach(func(key resourceKey, state *resourceState) bool {
+		if len(state.managers) == 0 {
+			return true
+		}
+		managers := make(map[string]int, len(state.managers))
+		for manager, count := range state.managers {
+			managers[manager] = count
+		}
+		snapshot.Managers[key.namespace+"/"+key.name] = managers
+		return true
+	})
+	return snapshot
+}
+
+// Restore restores the state of the watcher from the snapshot. It has to be called before Start.
+// The snapshot of a different resource type is rejected.
+func (w *Watcher) Restore(snapshot *Snapshot) error {
+	if snapshot.Statistics == nil {
+		return nil
+	}
+	if snapshot.Statistics.GroupVersionKind != w.groupVersionKind() {
+		return fmt.Errorf("snapshot of %s cannot be restored to the watcher of %s", snapshot.Statistics.GroupVersionKind.String(), w.gvk.String())
+	}
+
+	store := newStatisticsStore(len(w.store.shards))
+	store.relists.Store(int64(snapshot.Statistics.Relists))
+	store.watchErrors.Store(int64(snapshot.Statistics.WatchErrors))
+	for ns, nsStatistics := range snapshot.Statistics.Namespaces {
+		if nsStatistics == nil {
+			continue
+		}
+		for name, resStatistics := range nsStatistics.Resources {
+			if resStatistics == nil {
+				continue
+			}
+			store.shard(ns, name).resource(ns, name).statistics = resStatistics.DeepCopy()
+		}
+	}
+	for key, managers := range snapshot.Managers {
+		ns, name, _ := strings.Cut(key, "/")
+		state := store.shard(ns, name).resource(ns, name)
+		state.managers = make(map[string]int, len(managers))
+		for manager, count := range managers {
+			state.managers[manager] = count
+		}
+	}
+	for ns, histogram := range snapshot.Intervals {
+		if len(histogram.Buckets) != len(UpdateIntervalBuckets) {
+			// The buckets have been changed
+			continue
+		}
+		// The histograms are merged from all the shards, so the restored one can be put in any shard
+		store.shards[0].intervals[ns] = histogram.DeepCopy()
+	}
+	w.store = store
+	return nil
+}
+
+// intervalHistogramJSON is the JSON representation of IntervalHistogram, because JSON does not allow numbers as keys.
+type intervalHistogramJSON struct {
+	Count uint64  `json:"count"`
+	Sum   float64 `json:"sum"`
+	// Buckets holds the cumulative counts in the order of UpdateIntervalBuckets.
+	Buckets []uint64 `json:"buckets"`
+}
+
+func (h *IntervalHistogram) MarshalJSON() ([]byte, error) {
+	out := intervalHistogramJSON{
+		Count:   h.Count,
+		Sum:     h.Sum,
+		Buckets: make([]uint64, len(UpdateIntervalBuckets)),
+	}
+	for i, bound := range UpdateIntervalBuckets {
+		out.Buckets[i] = h.Buckets[bound]
+	}
+	return json.Marshal(out)
+}
+
+func (h *IntervalHistogram) UnmarshalJSON(data []byte) error {
+	var in intervalHistogramJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	h.Count = in.Count
+	h.Sum = in.Sum
+	h.Buckets = make(map[float64]uint64, len(in.Buckets))
+	for i, count := range in.Buckets {
+		if i >= len(UpdateIntervalBuckets) {
+			break
+		}
+		h.Buckets[UpdateIntervalBuckets[i]] = count
+	}
+	return nil
+}
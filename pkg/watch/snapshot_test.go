@@ -0,0 +1,62 @@
+package watch
+
+import (
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var _ = Describe("Test Snapshot", func() {
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	withManager := func(resourceVersion, manager string, t time.Time) *metav1.PartialObjectMetadata {
+		return &metav1.PartialObjectMetadata{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:       "default",
+				Name:            "test",
+				ResourceVersion: resourceVersion,
+				ManagedFields: []metav1.ManagedFieldsEntry{
+					{Manager: manager, Operation: metav1.ManagedFieldsOperationUpdate, Time: &metav1.Time{Time: t}},
+				},
+			},
+		}
+	}
+
+	It("should restore the state of the watcher", func() {
+		watcher := NewWatcher(ctrl.Log.WithName("snapshot-test"), nil, gvk, labels.Everything(), labels.Everything(), false)
+		now := time.Now()
+		v1 := withManager("1", "controller", now)
+		v2 := withManager("2", "controller", now.Add(time.Second))
+		v3 := withManager("3", "kubectl", now.Add(2*time.Second))
+		watcher.OnEvent(&SourceEvent{Type: "add", Object: v1})
+		watcher.OnEvent(&SourceEvent{Type: "update", Object: v2, OldObject: v1})
+		watcher.OnEvent(&SourceEvent{Type: "update", Object: v3, OldObject: v2})
+
+		data, err := json.Marshal(watcher.Snapshot())
+		Expect(err).ShouldNot(HaveOccurred())
+		snapshot := &Snapshot{}
+		Expect(json.Unmarshal(data, snapshot)).Should(Succeed())
+
+		restored := NewWatcher(ctrl.Log.WithName("snapshot-test"), nil, gvk, labels.Everything(), labels.Everything(), false)
+		Expect(restored.Restore(snapshot)).Should(Succeed())
+		Expect(restored.Statistics()).Should(Equal(watcher.Statistics()))
+		Expect(restored.ManagerUpdates("default", "test")).Should(Equal(map[string]int{"controller": 1, "kubectl": 1}))
+		Expect(restored.UpdateIntervals()).Should(Equal(watcher.UpdateIntervals()))
+
+		// Counting continues from the restored values
+		restored.OnEvent(&SourceEvent{Type: "update", Object: withManager("4", "kubectl", now.Add(3*time.Second)), OldObject: v3})
+		Expect(restored.Statistics().Namespaces["default"].Resources["test"].UpdateCount).Should(Equal(3))
+		Expect(restored.ManagerUpdates("default", "test")).Should(Equal(map[string]int{"controller": 1, "kubectl": 2}))
+	})
+
+	It("should reject the snapshot of another resource type", func() {
+		watcher := NewWatcher(ctrl.Log.WithName("snapshot-test"), nil, gvk, labels.Everything(), labels.Everything(), false)
+		other := NewWatcher(ctrl.Log.WithName("snapshot-test"), nil, schema.GroupVersionKind{Version: "v1", Kind: "Secret"}, labels.Everything(), labels.Everything(), false)
+		Expect(other.Restore(watcher.Snapshot())).ShouldNot(Succeed())
+	})
+})
@@ -0,0 +1,56 @@
+package watch
+
+import (
+	"context"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SourceEvent is an event of a resource delivered by an EventSource.
+type SourceEvent struct {
+	// Type is "add", "update" or "delete".
+	Type   string
+	Object *metav1.PartialObjectMetadata
+	// OldObject is the previous state of the object. It is only given for update events.
+	OldObject *metav1.PartialObjectMetadata
+	// Initial is true if the object is notified from the initial list rather than created after that.
+	Initial bool
+	// Time is when the event occurred. If this is zero, the time of handling the event is used.
+	Time time.Time
+}
+
+// EventHandler handles the events delivered by an EventSource. Watcher and BlameWatcher implement it.
+type EventHandler interface {
+	OnEvent(event *SourceEvent)
+	// OnRelist is called when the source lists the resources again (e.g. the watch has expired).
+	OnRelist()
+	// OnWatchError is called when the watch of the resources fails.
+	OnWatchError(err error)
+}
+
+// EventSource delivers the events of the resources of a single GroupVersionKind to a handler.
+type EventSource interface {
+	// Start starts delivering the events to the handler until ctx is done. It does not block.
+	Start(ctx context.Context, handler EventHandler) error
+	// HasSynced returns true if the initial list of the resources has been delivered.
+	HasSynced() bool
+}
+
+// ObjectGetter is implemented by the sources keeping the latest state of the objects.
+type ObjectGetter interface {
+	GetObject(namespace, name string) (*metav1.PartialObjectMetadata, bool)
+}
+
+// isResync returns true if the update event is caused by relist or resync without any changes.
+func isResync(oldObj, newObj interface{}) bool {
+	oldMeta, ok := oldObj.(*metav1.PartialObjectMetadata)
+	if !ok {
+		return false
+	}
+	newMeta, ok := newObj.(*metav1.PartialObjectMetadata)
+	if !ok {
+		return false
+	}
+	return oldMeta.ResourceVersion == newMeta.ResourceVersion
+}
@@ -4,11 +4,19 @@ import (
 	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 type Statistics struct {
 	GroupVersionKind metav1.GroupVersionKind         `json:"gvk"`
+	Cluster          string                          `json:"cluster,omitempty"`
 	Namespaces       map[string]*NamespaceStatistics `json:"namespaces"`
+
+	// Relists is the number of times the informer listed the resources again after the initial list,
+	// e.g. when the watch connection is dropped for a long time.
+	Relists int `json:"relists,omitempty"`
+	// WatchErrors is the number of errors of the list and watch requests.
+	WatchErrors int `json:"watchErrors,omitempty"`
 }
 
 type NamespaceStatistics struct {
@@ -19,6 +27,23 @@ type ResourceStatistics struct {
 	AddCount    int `json:"add"`
 	DeleteCount int `json:"delete"`
 	UpdateCount int `json:"update"`
+
+	// MinInterval and MedianInterval are the minimum and median seconds between consecutive updates.
+	// The median is calculated from the recent updates.
+	MinInterval    float64 `json:"minIntervalSeconds,omitempty"`
+	MedianInterval float64 `json:"medianIntervalSeconds,omitempty"`
+
+	// Users and UserAgents are only available when the statistics are made from audit logs.
+	Users      map[string]*ResourceStatistics `json:"users,omitempty"`
+	UserAgents map[string]*ResourceStatistics `json:"userAgents,omitempty"`
+}
+
+// TopResource is a resource ranked by the number of updates.
+type TopResource struct {
+	GroupVersionKind   metav1.GroupVersionKind `json:"gvk"`
+	Namespace          string                  `json:"namespace,omitempty"`
+	Name               string                  `json:"name"`
+	ResourceStatistics `json:",inline"`
 }
 
 func (in *Statistics) DeepCopy() *Statistics {
@@ -71,19 +96,89 @@ func (in *NamespaceStatistics) DeepCopyInto(out *NamespaceStatistics) {
 			} else {
 				in, out := &val, &outVal
 				*out = new(ResourceStatistics)
-				**out = **in
+				(*in).DeepCopyInto(*out)
 			}
 			(*out)[key] = outVal
 		}
 	}
 }
 
+func (in *ResourceStatistics) DeepCopy() *ResourceStatistics {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceStatistics)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *ResourceStatistics) DeepCopyInto(out *ResourceStatistics) {
+	*out = *in
+	if in.Users != nil {
+		in, out := &in.Users, &out.Users
+		*out = make(map[string]*ResourceStatistics, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.UserAgents != nil {
+		in, out := &in.UserAgents, &out.UserAgents
+		*out = make(map[string]*ResourceStatistics, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+}
+
 type ManagerStatistics struct {
 	UpdateCount int `json:"update"`
+
+	// Users and UserAgents are only available when the statistics are made from audit logs.
+	Users      map[string]*ManagerStatistics `json:"users,omitempty"`
+	UserAgents map[string]*ManagerStatistics `json:"userAgents,omitempty"`
+}
+
+func (in *ManagerStatistics) DeepCopy() *ManagerStatistics {
+	if in == nil {
+		return nil
+	}
+	out := new(ManagerStatistics)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *ManagerStatistics) DeepCopyInto(out *ManagerStatistics) {
+	*out = *in
+	if in.Users != nil {
+		in, out := &in.Users, &out.Users
+		*out = make(map[string]*ManagerStatistics, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.UserAgents != nil {
+		in, out := &in.UserAgents, &out.UserAgents
+		*out = make(map[string]*ManagerStatistics, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
 }
 
 type BlameStatistics struct {
+	Cluster               string `json:"cluster,omitempty"`
+	IncarnationStatistics `json:",inline"`
+	History               []IncarnationStatistics `json:"history,omitempty"`
+}
+
+// IncarnationStatistics represents the updates made to a single incarnation of a resource, identified by its UID.
+type IncarnationStatistics struct {
+	UID          types.UID                     `json:"uid,omitempty"`
+	CreatedAt    *time.Time                    `json:"createdAt,omitempty"`
+	CreatedBy    string                        `json:"createdBy,omitempty"`
+	DeletedAt    *time.Time                    `json:"deletedAt,omitempty"`
 	Managers     map[string]*ManagerStatistics `json:"managers"`
+	Unattributed int                           `json:"unattributed"`
 	LatestUpdate time.Time                     `json:"lastUpdate"`
 }
 
@@ -98,7 +193,37 @@ func (in *BlameStatistics) DeepCopy() *BlameStatistics {
 
 func (in *BlameStatistics) DeepCopyInto(out *BlameStatistics) {
 	*out = *in
+	in.IncarnationStatistics.DeepCopyInto(&out.IncarnationStatistics)
+	if in.History != nil {
+		in, out := &in.History, &out.History
+		*out = make([]IncarnationStatistics, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+func (in *IncarnationStatistics) DeepCopy() *IncarnationStatistics {
+	if in == nil {
+		return nil
+	}
+	out := new(IncarnationStatistics)
+	in.DeepCopyInto(out)
+	return out
+}
 
+func (in *IncarnationStatistics) DeepCopyInto(out *IncarnationStatistics) {
+	*out = *in
+	if in.CreatedAt != nil {
+		in, out := &in.CreatedAt, &out.CreatedAt
+		*out = new(time.Time)
+		**out = **in
+	}
+	if in.DeletedAt != nil {
+		in, out := &in.DeletedAt, &out.DeletedAt
+		*out = new(time.Time)
+		**out = **in
+	}
 	if in.Managers != nil {
 		in, out := &in.Managers, &out.Managers
 		*out = make(map[string]*ManagerStatistics, len(*in))
@@ -109,10 +234,9 @@ func (in *BlameStatistics) DeepCopyInto(out *BlameStatistics) {
 			} else {
 				in, out := &val, &outVal
 				*out = new(ManagerStatistics)
-				*out = *in
+				(*in).DeepCopyInto(*out)
 			}
 			(*out)[key] = outVal
 		}
 	}
-	return
 }
@@ -0,0 +1,135 @@
+package watch
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// defaultShards is the minimum number of shards of a statisticsStore.
+const defaultShards = 32
+
+// statisticsStore holds the state of the resources of a Watcher in shards selected by the hash of their keys,
+// so that the events of different resources and the reads of the statistics rarely wait for each other.
+type statisticsStore struct {
+	shards      []storeShard
+	mask        uint32
+	relists     atomic.Int64
+	watchErrors atomic.Int64
+}
+
+type storeShard struct {
+	mu        sync.RWMutex
+	resources map[resourceKey]*resourceState
+	// intervals holds the histograms of the update intervals of the resources in the shard for each namespace.
+	intervals map[string]*IntervalHistogram
+	// padding keeps the locks of adjacent shards on different cache lines
+	_ [64]byte
+}
+
+type resourceKey struct {
+	namespace string
+	name      string
+}
+
+// resourceState is the state of a resource. statistics is nil while the resource is not in the statistics,
+// e.g. when only its managers are restored from a snapshot.
+type resourceState struct {
+	statistics *ResourceStatistics
+	tracker    *intervalTracker
+	managers   map[string]int
+	switches   managerSwitches
+}
+
+// newStatisticsStore creates a statisticsStore with the number of shards rounded up to a power of two.
+// If shards is 0, it is decided from the number of CPUs.
+func newStatisticsStore(shards int) *statisticsStore {
+	if shards <= 0 {
+		shards = defaultShards
+		if n := 4 * runtime.GOMAXPROCS(0); n > shards {
+			shards = n
+		}
+	}
+	n := 1
+	for n < shards {
+		n <<= 1
+	}
+	s := &statisticsStore{
+		shards: make([]storeShard, n),
+		mask:   uint32(n - 1),
+	}
+	for i := range s.shards {
+		s.shards[i].resources = make(map[resourceKey]*resourceState)
+		s.shards[i].intervals = make(map[string]*IntervalHistogram)
+	}
+	return s
+}
+
+// shard returns the shard of the resource. The key is hashed with FNV-1a without allocating.
+func (s *statisticsStore) shard(namespace, name string) *storeShard {
+	const offset, prime = 2166136261, 16777619
+	h := uint32(offset)
+	for i := 0; i < len(namespace); i++ {
+		h = (h ^ uint32(namespace[i])) * prime
+	}
+	h = (h ^ '/') * prime
+	for i := 0; i < len(name); i++ {
+		h = (h ^ uint32(name[i])) * prime
+	}
+	return &s.shards[h&s.mask]
+}
+
+// resource returns the state of the resource, creating it if it does not exist. The lock of the shard has to be held.
+func (sh *storeShard) resource(namespace, name string) *resourceState {
+	key := resourceKey{namespace: namespace, name: name}
+	state, ok := sh.resources[key]
+	if !ok {
+		state = &resourceState{}
+		sh.resources[key] = state
+	}
+	return state
+}
+
+// histogram returns the histogram of the update intervals of the namespace, creating it if it does not exist.
+// The lock of the shard has to be held.
+func (sh *storeShard) histogram(namespace string) *IntervalHistogram {
+	histogram, ok := sh.intervals[namespace]
+	if !ok {
+		histogram = newIntervalHistogram()
+		sh.intervals[namespace] = histogram
+	}
+	return histogram
+}
+
+// forEach calls fn with the state of each resource until fn returns false.
+// Only the read lock of one shard is held at a time.
+func (s *statisticsStore) forEach(fn func(key resourceKey, state *resourceState) bool) {
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.mu.RLock()
+		for key, state := range sh.resources {
+			if !fn(key, state) {
+				sh.mu.RUnlock()
+				return
+			}
+		}
+		sh.mu.RUnlock()
+	}
+}
+
+// intervals returns the histograms of the update intervals for each namespace merged from all the shards.
+func (s *statisticsStore) intervals() map[string]*IntervalHistogram {
+	result := make(map[string]*IntervalHistogram)
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.mu.RLock()
+		for ns, histogram := range sh.intervals {
+			if _, ok := result[ns]; !ok {
+				result[ns] = newIntervalHistogram()
+			}
+			result[ns].merge(histogram)
+		}
+		sh.mu.RUnlock()
+	}
+	return result
+}
@@ -0,0 +1,165 @@
+package watch
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// benchmarkResources is the number of resources updated in the benchmarks.
+const benchmarkResources = 10000
+
+// benchmarkShards compares a single lock for all the resources with the default sharding.
+var benchmarkShards = []struct {
+	name   string
+	shards int
+}{
+	{name: "single-lock", shards: 1},
+	{name: "sharded", shards: 0},
+}
+
+func newBenchmarkWatcher(shards int) (*Watcher, []*SourceEvent) {
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	watcher := NewWatcherWithSource(logr.Discard(), nil, "", gvk, labels.Everything(), false)
+	watcher.store = newStatisticsStore(shards)
+
+	base := time.Now()
+	events := make([]*SourceEvent, benchmarkResources)
+	for i := range events {
+		events[i] = &SourceEvent{
+			Type: "update",
+			Object: &metav1.PartialObjectMetadata{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: fmt.Sprintf("ns-%d", i%100),
+					Name:      fmt.Sprintf("res-%d", i),
+					ManagedFields: []metav1.ManagedFieldsEntry{
+						{Manager: "my-operator", Operation: metav1.ManagedFieldsOperationUpdate, Time: &metav1.Time{Time: base}},
+					},
+				},
+			},
+		}
+		// Fill the statistics before the measurement
+		watcher.OnEvent(events[i])
+	}
+	return watcher, events
+}
+
+// sendEvents delivers the events to the watcher in parallel.
+func sendEvents(b *testing.B, watcher *Watcher, events []*SourceEvent) {
+	var next atomic.Int64
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			i := next.Add(1)
+			watcher.OnEvent(events[i%int64(len(events))])
+		}
+	})
+}
+
+// scrape collects the metrics of the watcher.
+func scrape(collector *Collector) {
+	ch := make(chan prometheus.Metric, 1024)
+	go func() {
+		collector.Collect(ch)
+		close(ch)
+	}()
+	for range ch {
+	}
+}
+
+// runInBackground calls fn repeatedly until the returned function is called,
+// which returns the number of the calls per second.
+func runInBackground(fn func()) (stop func() float64) {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	var calls int
+	start := time.Now()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				fn()
+				calls++
+			}
+		}
+	}()
+	return func() float64 {
+		close(done)
+		wg.Wait()
+		return float64(calls) / time.Since(start).Seconds()
+	}
+}
+
+func BenchmarkWatcherEvents(b *testing.B) {
+	for _, bs := range benchmarkShards {
+		b.Run(bs.name, func(b *testing.B) {
+			watcher, events := newBenchmarkWatcher(bs.shards)
+			b.ResetTimer()
+			sendEvents(b, watcher, events)
+		})
+	}
+}
+
+func BenchmarkWatcherEventsWithScrapes(b *testing.B) {
+	for _, bs := range benchmarkShards {
+		b.Run(bs.name, func(b *testing.B) {
+			watcher, events := newBenchmarkWatcher(bs.shards)
+			collector := NewCollector(func() []*Watcher { return []*Watcher{watcher} })
+			b.ResetTimer()
+			stop := runInBackground(func() { scrape(collector) })
+			sendEvents(b, watcher, events)
+			b.StopTimer()
+			b.ReportMetric(stop(), "scrapes/s")
+		})
+	}
+}
+
+func BenchmarkWatcherScrapesWithEvents(b *testing.B) {
+	for _, bs := range benchmarkShards {
+		b.Run(bs.name, func(b *testing.B) {
+			watcher, events := newBenchmarkWatcher(bs.shards)
+			collector := NewCollector(func() []*Watcher { return []*Watcher{watcher} })
+			var next int
+			b.ResetTimer()
+			stop := runInBackground(func() {
+				watcher.OnEvent(events[next%len(events)])
+				next++
+			})
+			for i := 0; i < b.N; i++ {
+				scrape(collector)
+			}
+			b.StopTimer()
+			b.ReportMetric(stop(), "events/s")
+		})
+	}
+}
+
+func BenchmarkWatcherStatistics(b *testing.B) {
+	for _, bs := range benchmarkShards {
+		b.Run(bs.name, func(b *testing.B) {
+			watcher, events := newBenchmarkWatcher(bs.shards)
+			var next int
+			b.ResetTimer()
+			stop := runInBackground(func() {
+				watcher.OnEvent(events[next%len(events)])
+				next++
+			})
+			for i := 0; i < b.N; i++ {
+				watcher.Statistics()
+			}
+			b.StopTimer()
+			b.ReportMetric(stop(), "events/s")
+		})
+	}
+}
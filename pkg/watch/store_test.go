@@ -0,0 +1,69 @@
+package watch
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var _ = Describe("Test statistics store", func() {
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	base := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+	newEvent := func(event, namespace, name string, t time.Time) *SourceEvent {
+		return &SourceEvent{
+			Type: event,
+			Object: &metav1.PartialObjectMetadata{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			},
+			Time: t,
+		}
+	}
+
+	It("should round up the number of shards to a power of two", func() {
+		Expect(newStatisticsStore(1).shards).Should(HaveLen(1))
+		Expect(newStatisticsStore(5).shards).Should(HaveLen(8))
+		Expect(len(newStatisticsStore(0).shards)).Should(BeNumerically(">=", defaultShards))
+	})
+
+	It("should count the concurrent events of the resources in different shards", func() {
+		watcher := NewWatcherWithSource(ctrl.Log.WithName("store-test"), nil, "", gvk, labels.Everything(), false)
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					name := fmt.Sprintf("res-%d", j)
+					watcher.OnEvent(newEvent("update", fmt.Sprintf("ns-%d", j%2), name, base.Add(time.Duration(i*100+j)*time.Second)))
+					watcher.Statistics()
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		statistics := watcher.Statistics()
+		Expect(statistics.Namespaces).Should(HaveLen(2))
+		total := 0
+		for _, nsStatistics := range statistics.Namespaces {
+			Expect(nsStatistics.Resources).Should(HaveLen(50))
+			for _, resStatistics := range nsStatistics.Resources {
+				Expect(resStatistics.UpdateCount).Should(Equal(8))
+				total += resStatistics.UpdateCount
+			}
+		}
+		Expect(total).Should(Equal(800))
+
+		// The histograms of the shards are merged for each namespace
+		intervals := watcher.UpdateIntervals()
+		Expect(intervals).Should(HaveLen(2))
+		Expect(intervals["ns-0"].Count + intervals["ns-1"].Count).Should(Equal(uint64(700)))
+	})
+})
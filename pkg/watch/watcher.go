@@ -2,7 +2,9 @@ package watch
 
 import (
 	"context"
+	"errors"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -11,55 +13,84 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
-	toolscache "k8s.io/client-go/tools/cache"
-	cache "sigs.k8s.io/controller-runtime/pkg/cache"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 type Watcher struct {
-	logger      logr.Logger
-	kube        *client.KubeClient
-	gvk         schema.GroupVersionKind
-	nsSelector  labels.Selector
-	resSelector labels.Selector
+	logger          logr.Logger
+	namespaces      ctrlclient.Reader
+	source          EventSource
+	gvk             schema.GroupVersionKind
+	nsSelector      labels.Selector
+	resSelector     labels.Selector
+	includeExisting bool
+	cluster         string
+	sink            EventSink
+	recorder        *SessionRecorder
 
-	startTime    time.Time
-	informer     cache.Informer
-	registration toolscache.ResourceEventHandlerRegistration
+	cancel context.CancelFunc
 
-	mu         sync.RWMutex
-	statistics Statistics
+	store *statisticsStore
+
+	mu        sync.Mutex
+	observers map[int]func(Delta)
+	nextID    int
+	// observerList is a copy of observers read without the lock on every event
+	observerList atomic.Pointer[[]func(Delta)]
 }
 
-func NewWatcher(logger logr.Logger, kube *client.KubeClient, gvk schema.GroupVersionKind, nsSelector labels.Selector, resSelector labels.Selector) *Watcher {
-	statistics := Statistics{}
-	statistics.GroupVersionKind = metav1.GroupVersionKind{
-		Group:   gvk.Group,
-		Version: gvk.Version,
-		Kind:    gvk.Kind,
+// NewWatcher creates a Watcher that watches the resources with an informer. If includeExisting is true,
+// the resources existing at the start of watching are included in the statistics with zero counts as a baseline snapshot.
+func NewWatcher(logger logr.Logger, kube *client.KubeClient, gvk schema.GroupVersionKind, nsSelector labels.Selector, resSelector labels.Selector, includeExisting bool) *Watcher {
+	var source EventSource
+	var cluster string
+	if kube != nil {
+		source = NewInformerSource(kube, gvk)
+		cluster = kube.Name
+	}
+	w := NewWatcherWithSource(logger, source, cluster, gvk, resSelector, includeExisting)
+	if kube != nil {
+		w.SetNamespaceSelector(kube.Cluster.GetClient(), nsSelector)
 	}
-	statistics.Namespaces = make(map[string]*NamespaceStatistics)
+	return w
+}
 
+// NewWatcherWithSource creates a Watcher that counts the events delivered by the source.
+// If source is nil, the events have to be passed to OnEvent directly.
+func NewWatcherWithSource(logger logr.Logger, source EventSource, cluster string, gvk schema.GroupVersionKind, resSelector labels.Selector, includeExisting bool) *Watcher {
 	return &Watcher{
-		logger:      logger,
-		kube:        kube,
-		statistics:  statistics,
-		gvk:         gvk,
-		nsSelector:  nsSelector,
-		resSelector: resSelector,
+		logger:          logger,
+		source:          source,
+		gvk:             gvk,
+		nsSelector:      labels.Everything(),
+		resSelector:     resSelector,
+		includeExisting: includeExisting,
+		cluster:         cluster,
+		store:           newStatisticsStore(0),
+		observers:       make(map[int]func(Delta)),
 	}
 }
 
-func (w *Watcher) handle(obj interface{}, event string) {
-	meta := obj.(*metav1.PartialObjectMetadata)
+// OnEvent counts the event delivered by the source.
+func (w *Watcher) OnEvent(event *SourceEvent) {
+	meta := event.Object
+	if meta == nil {
+		return
+	}
+	now := event.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
 
-	w.logger.V(3).Info("Event", "event", event, "gvk", meta.GroupVersionKind(), "namespace", meta.Namespace, "name", meta.Name)
-	if event == "add" {
-		if meta.CreationTimestamp.Time.Before(w.startTime) {
-			// Ignore add events for resources created before start of watching
-			w.logger.V(3).Info("Ignore resources created before start of watch", "start", w.startTime, "creation", meta.CreationTimestamp)
-			return
-		}
+	w.logger.V(3).Info("Event", "event", event.Type, "gvk", meta.GroupVersionKind(), "namespace", meta.Namespace, "name", meta.Name)
+	existing := event.Type == "add" && event.Initial
+	if w.recorder != nil {
+		w.recorder.record(now, w.cluster, w.gvk, event.Type, existing, meta)
+	}
+	if existing && !w.includeExisting {
+		// Ignore add events for resources existing before start of watching
+		w.logger.V(3).Info("Ignore resources in the initial list", "namespace", meta.Namespace, "name", meta.Name)
+		return
 	}
 
 	if !w.resSelector.Matches(labels.Set(meta.Labels)) {
@@ -67,7 +98,7 @@ func (w *Watcher) handle(obj interface{}, event string) {
 	}
 	if !w.nsSelector.Empty() && meta.Namespace != "" {
 		ns := &corev1.Namespace{}
-		err := w.kube.Cluster.GetClient().Get(context.TODO(), ctrlclient.ObjectKey{Name: meta.Namespace}, ns)
+		err := w.namespaces.Get(context.TODO(), ctrlclient.ObjectKey{Name: meta.Namespace}, ns)
 		if err != nil {
 			w.logger.Error(err, "failed to get namespace", "namespace", meta.Namespace)
 			return
@@ -77,66 +108,290 @@ func (w *Watcher) handle(obj interface{}, event string) {
 		}
 	}
 
-	w.mu.Lock()
-	defer w.mu.Unlock()
-
-	if _, ok := w.statistics.Namespaces[meta.Namespace]; !ok {
-		ks := &NamespaceStatistics{}
-		ks.Resources = make(map[string]*ResourceStatistics)
-		w.statistics.Namespaces[meta.Namespace] = ks
+	manager, counted, resStatistics := w.count(event.OldObject, meta, event.Type, existing, now)
+	if !counted {
+		return
 	}
-	info := w.statistics.Namespaces[meta.Namespace]
+	w.notify(Delta{
+		Time:       now,
+		Namespace:  meta.Namespace,
+		Name:       meta.Name,
+		Type:       event.Type,
+		Manager:    manager,
+		Events:     1,
+		Statistics: resStatistics,
+	})
+	if w.sink != nil {
+		w.sink.Record(Event{
+			Time:             now,
+			Cluster:          w.cluster,
+			GroupVersionKind: w.gvk,
+			Namespace:        meta.Namespace,
+			Name:             meta.Name,
+			Type:             event.Type,
+			Manager:          manager,
+		})
+	}
+}
 
-	if _, ok := info.Resources[meta.Name]; !ok {
-		info.Resources[meta.Name] = &ResourceStatistics{}
+// OnRelist counts the relist of the resources by the source.
+func (w *Watcher) OnRelist() {
+	w.store.relists.Add(1)
+	w.logger.Info("relist resources", "gvk", w.gvk.String())
+}
+
+// OnWatchError counts the failure of the watch of the source.
+func (w *Watcher) OnWatchError(err error) {
+	w.store.watchErrors.Add(1)
+}
+
+// count updates the statistics with the event, and returns the manager that performed it if known
+// and the statistics of the resource after the event.
+// If the event is not counted (i.e. the object existed before start of watching), false is returned.
+func (w *Watcher) count(oldObj, meta *metav1.PartialObjectMetadata, event string, existing bool, now time.Time) (string, bool, ResourceStatistics) {
+	shard := w.store.shard(meta.Namespace, meta.Name)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	state := shard.resource(meta.Namespace, meta.Name)
+	if state.statistics == nil {
+		state.statistics = &ResourceStatistics{}
+	}
+	resInfo := state.statistics
+	if existing {
+		return "", false, ResourceStatistics{}
 	}
-	resInfo := info.Resources[meta.Name]
 
+	var manager string
 	switch event {
 	case "add":
 		resInfo.AddCount += 1
+		manager, _ = attributeWrite(nil, meta.ManagedFields)
 	case "update":
 		resInfo.UpdateCount += 1
+		trackInterval(shard, meta.Namespace, state, now)
+		manager = trackManager(oldObj, meta, state)
 	case "delete":
 		resInfo.DeleteCount += 1
+		state.tracker = nil
+		state.managers = nil
+		state.switches = managerSwitches{}
 	}
+	return manager, true, *resInfo
 }
 
-func (w *Watcher) Statistics() *Statistics {
-	w.mu.RLock()
-	defer w.mu.RUnlock()
+// trackManager counts the update for the manager that performed it, and returns the manager.
+func trackManager(oldObj, meta *metav1.PartialObjectMetadata, state *resourceState) string {
+	var oldFields []metav1.ManagedFieldsEntry
+	if oldObj != nil {
+		oldFields = oldObj.ManagedFields
+	}
+	manager, _ := attributeWrite(oldFields, meta.ManagedFields)
+	if manager == "" {
+		return ""
+	}
+
+	if state.managers == nil {
+		state.managers = make(map[string]int)
+	}
+	state.managers[manager] += 1
 
-	return w.statistics.DeepCopy()
+	if last := state.switches.last; last != "" && last != manager {
+		state.switches.count += 1
+	}
+	state.switches.last = manager
+	return manager
 }
 
-func (w *Watcher) Start(ctx context.Context) error {
-	w.logger.Info("start watcher", "gvk", w.gvk.String(), "nsSelector", w.nsSelector.String(), "resSelector", w.resSelector.String())
-	w.startTime = time.Now()
+// managerSwitches counts how many times the manager that updates an object has changed.
+type managerSwitches struct {
+	last  string
+	count int
+}
+
+// ManagerSwitches returns how many times the manager that updates the resource has changed.
+// Managers overwriting each other's changes (e.g. two controllers fighting over a field) switch frequently.
+func (w *Watcher) ManagerSwitches(namespace, name string) int {
+	shard := w.store.shard(namespace, name)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	if state, ok := shard.resources[resourceKey{namespace: namespace, name: name}]; ok {
+		return state.switches.count
+	}
+	return 0
+}
 
-	meta := &metav1.PartialObjectMetadata{}
+// ManagerUpdates returns the number of updates performed by each manager to the resource.
+func (w *Watcher) ManagerUpdates(namespace, name string) map[string]int {
+	shard := w.store.shard(namespace, name)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	state, ok := shard.resources[resourceKey{namespace: namespace, name: name}]
+	if !ok {
+		return map[string]int{}
+	}
+	result := make(map[string]int, len(state.managers))
+	for manager, count := range state.managers {
+		result[manager] = count
+	}
+	return result
+}
+
+// Object returns the latest metadata of the resource kept by the source.
+// It returns false if the source does not keep the objects.
+func (w *Watcher) Object(namespace, name string) (*metav1.PartialObjectMetadata, bool) {
+	getter, ok := w.source.(ObjectGetter)
+	if !ok {
+		return nil, false
+	}
+	meta, ok := getter.GetObject(namespace, name)
+	if !ok {
+		return nil, false
+	}
+	meta = meta.DeepCopy()
 	meta.SetGroupVersionKind(w.gvk)
-	informer, err := w.kube.Cluster.GetCache().GetInformer(ctx, meta)
-	if err != nil {
-		return err
-	}
-	w.informer = informer
-
-	reg, err := informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
-		AddFunc: func(obj interface{}) {
-			w.handle(obj, "add")
-		},
-		UpdateFunc: func(oldObj, newObj interface{}) {
-			w.handle(newObj, "update")
-		},
-		DeleteFunc: func(obj interface{}) {
-			w.handle(obj, "delete")
-		},
+	return meta, true
+}
+
+// trackInterval records the interval from the previous update of the object.
+func trackInterval(shard *storeShard, namespace string, state *resourceState, now time.Time) {
+	if state.tracker == nil {
+		state.tracker = &intervalTracker{}
+	}
+	tracker := state.tracker
+	interval, ok := tracker.update(now)
+	if !ok {
+		return
+	}
+
+	shard.histogram(namespace).observe(interval)
+	state.statistics.MinInterval = tracker.min.Seconds()
+	state.statistics.MedianInterval = tracker.median().Seconds()
+}
+
+// Resource returns the statistics of a single resource without copying the statistics of the others.
+func (w *Watcher) Resource(namespace, name string) (ResourceStatistics, bool) {
+	shard := w.store.shard(namespace, name)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	state, ok := shard.resources[resourceKey{namespace: namespace, name: name}]
+	if !ok || state.statistics == nil {
+		return ResourceStatistics{}, false
+	}
+	return *state.statistics.DeepCopy(), true
+}
+
+// Range calls fn with the statistics of each resource until fn returns false.
+// It holds the lock of a part of the statistics instead of copying them, so fn must not block or call the methods of the Watcher.
+func (w *Watcher) Range(fn func(namespace, name string, statistics *ResourceStatistics) bool) {
+	w.store.forEach(func(key resourceKey, state *resourceState) bool {
+		if state.statistics == nil {
+			return true
+		}
+		return fn(key.namespace, key.name, state.statistics)
+	})
+}
+
+// RangeManagers calls fn with the number of updates performed by each manager and the number of manager switches
+// of each resource until fn returns false. Like Range, fn must not block, modify managers or call the methods of the Watcher.
+func (w *Watcher) RangeManagers(fn func(namespace, name string, managers map[string]int, switches int) bool) {
+	w.store.forEach(func(key resourceKey, state *resourceState) bool {
+		if len(state.managers) == 0 && state.switches.count == 0 {
+			return true
+		}
+		return fn(key.namespace, key.name, state.managers, state.switches.count)
 	})
-	w.registration = reg
+}
+
+// ListWatchErrors returns the number of relists and watch errors of the source.
+func (w *Watcher) ListWatchErrors() (relists int, watchErrors int) {
+	return int(w.store.relists.Load()), int(w.store.watchErrors.Load())
+}
+
+// Statistics returns a copy of the statistics of all the resources.
+func (w *Watcher) Statistics() *Statistics {
+	relists, watchErrors := w.ListWatchErrors()
+	statistics := &Statistics{
+		GroupVersionKind: w.groupVersionKind(),
+		Cluster:          w.cluster,
+		Namespaces:       make(map[string]*NamespaceStatistics),
+		Relists:          relists,
+		WatchErrors:      watchErrors,
+	}
+	w.Range(func(namespace, name string, resStatistics *ResourceStatistics) bool {
+		nsStatistics, ok := statistics.Namespaces[namespace]
+		if !ok {
+			nsStatistics = &NamespaceStatistics{Resources: make(map[string]*ResourceStatistics)}
+			statistics.Namespaces[namespace] = nsStatistics
+		}
+		nsStatistics.Resources[name] = resStatistics.DeepCopy()
+		return true
+	})
+	return statistics
+}
 
-	return err
+// UpdateIntervals returns the histograms of the intervals between consecutive updates of objects for each namespace.
+func (w *Watcher) UpdateIntervals() map[string]*IntervalHistogram {
+	return w.store.intervals()
+}
+
+// SetNamespaceSelector sets the selector of the namespaces to which the counted resources belong.
+// The labels of the namespaces are read from reader. It has to be called before Start.
+func (w *Watcher) SetNamespaceSelector(reader ctrlclient.Reader, selector labels.Selector) {
+	w.namespaces = reader
+	w.nsSelector = selector
+}
+
+// SetEventSink sets the sink to which the counted events are sent. It has to be called before Start.
+func (w *Watcher) SetEventSink(sink EventSink) {
+	w.sink = sink
+}
+
+// SetSessionRecorder sets the recorder to which the raw events are written before being filtered.
+// It has to be called before Start.
+func (w *Watcher) SetSessionRecorder(recorder *SessionRecorder) {
+	w.recorder = recorder
+}
+
+// GroupVersionKind returns the GroupVersionKind of the watched resources.
+func (w *Watcher) GroupVersionKind() schema.GroupVersionKind {
+	return w.gvk
+}
+
+// Cluster returns the name of the watched cluster.
+func (w *Watcher) Cluster() string {
+	return w.cluster
+}
+
+func (w *Watcher) groupVersionKind() metav1.GroupVersionKind {
+	return metav1.GroupVersionKind{
+		Group:   w.gvk.Group,
+		Version: w.gvk.Version,
+		Kind:    w.gvk.Kind,
+	}
+}
+
+// HasSynced returns true if the source has delivered the initial list of the resources.
+func (w *Watcher) HasSynced() bool {
+	return w.source != nil && w.source.HasSynced()
+}
+
+func (w *Watcher) Start(ctx context.Context) error {
+	w.logger.Info("start watcher", "gvk", w.gvk.String(), "nsSelector", w.nsSelector.String(), "resSelector", w.resSelector.String())
+	if w.source == nil {
+		return errors.New("no event source")
+	}
+
+	ctx, w.cancel = context.WithCancel(ctx)
+	return w.source.Start(ctx, w)
 }
 
 func (w *Watcher) Stop() error {
-	return w.informer.RemoveEventHandler(w.registration)
+	if w.cancel != nil {
+		w.cancel()
+	}
+	return nil
 }
@@ -2,191 +2,148 @@ package watch
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	. "github.com/onsi/gomega/gstruct"
-	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	toolscache "k8s.io/client-go/tools/cache"
 	ctrl "sigs.k8s.io/controller-runtime"
-	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-var _ = Describe("Test Watcher", func() {
-	ctx := context.Background()
-	logger := ctrl.Log.WithName("watcher-test")
-	var watcher *Watcher
-
-	var startWatcher = func(resourceType string, nsSelector, resSelector labels.Selector) {
-		gvk, err := kubeClient.DetectGVK(resourceType)
-		Expect(err).NotTo(HaveOccurred())
-		watcher = NewWatcher(logger, kubeClient, *gvk, nsSelector, resSelector)
-
-		err = watcher.Start(ctx)
-		Expect(err).NotTo(HaveOccurred())
-
-		time.Sleep(1 * time.Second)
+var _ = Describe("Test Watcher events", func() {
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	newMeta := func(name, resourceVersion string) *metav1.PartialObjectMetadata {
+		return &metav1.PartialObjectMetadata{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:       "default",
+				Name:            name,
+				ResourceVersion: resourceVersion,
+			},
+		}
+	}
+	startWatcher := func(source *FakeSource, includeExisting bool) *Watcher {
+		watcher := NewWatcherWithSource(ctrl.Log.WithName("watcher-test"), source, "", gvk, labels.Everything(), includeExisting)
+		Expect(watcher.Start(context.Background())).Should(Succeed())
+		Expect(watcher.HasSynced()).Should(BeTrue())
+		return watcher
 	}
 
-	AfterEach(func() {
-		cli := kubeClient.Cluster.GetClient()
-		err := cli.DeleteAllOf(ctx, &corev1.ConfigMap{}, ctrlclient.InNamespace("default"))
-		Expect(err).ShouldNot(HaveOccurred())
+	It("should count deletes of tombstones", func() {
+		source := NewInformerSource(nil, gvk)
+		watcher := NewWatcherWithSource(ctrl.Log.WithName("watcher-test"), source, "", gvk, labels.Everything(), false)
+		source.handler = watcher
+		handler := source.eventHandler()
+		handler.OnDelete(newMeta("test", "1"))
+		handler.OnDelete(toolscache.DeletedFinalStateUnknown{Key: "default/test", Obj: newMeta("test", "2")})
 
-		err = cli.DeleteAllOf(ctx, &corev1.ConfigMap{}, ctrlclient.InNamespace("admin-ns"))
-		Expect(err).ShouldNot(HaveOccurred())
+		statistics := watcher.Statistics()
+		Expect(statistics.Namespaces["default"].Resources["test"].DeleteCount).Should(Equal(2))
+	})
 
-		err = cli.DeleteAllOf(ctx, &corev1.ConfigMap{}, ctrlclient.InNamespace("user-ns"))
-		Expect(err).ShouldNot(HaveOccurred())
+	It("should detect add events from the initial list of the informer", func() {
+		source := NewInformerSource(nil, gvk)
+		existing := newMeta("existing", "1")
+		existing.UID = "existing-uid"
+		source.initialObjects[existing.UID] = existing.ResourceVersion
+		created := newMeta("created", "2")
+		created.UID = "created-uid"
+		recreated := newMeta("existing", "3")
+		recreated.UID = "existing-uid"
+
+		Expect(source.isInitialObject(existing)).Should(BeTrue())
+		Expect(source.isInitialObject(created)).Should(BeFalse())
+		Expect(source.isInitialObject(recreated)).Should(BeFalse())
+	})
 
-		Eventually(func(g Gomega) {
-			for _, ns := range []string{"default", "admin-ns", "user-ns"} {
-				cms := &corev1.ConfigMapList{}
-				err = cli.List(ctx, cms, ctrlclient.InNamespace(ns))
-				g.Expect(err).ShouldNot(HaveOccurred())
-				g.Expect(cms.Items).Should(HaveLen(0))
+	It("should ignore add events from the initial list", func() {
+		for _, includeExisting := range []bool{false, true} {
+			existing := newMeta("existing", "1")
+			source := NewFakeSource(existing)
+			watcher := startWatcher(source, includeExisting)
+			source.Add(newMeta("created", "2"))
+
+			statistics := watcher.Statistics()
+			Expect(statistics.Namespaces["default"].Resources["created"].AddCount).Should(Equal(1))
+			if includeExisting {
+				Expect(statistics.Namespaces["default"].Resources).Should(HaveKeyWithValue("existing", PointTo(MatchFields(IgnoreExtras, Fields{
+					"AddCount":    Equal(0),
+					"UpdateCount": Equal(0),
+					"DeleteCount": Equal(0),
+				}))))
+			} else {
+				Expect(statistics.Namespaces["default"].Resources).ShouldNot(HaveKey("existing"))
 			}
-		}).Should(Succeed())
-
-		err = watcher.Stop()
-		Expect(err).ShouldNot(HaveOccurred())
+		}
 	})
 
-	Context("Watcher with everything", func() {
-		BeforeEach(func() {
-			startWatcher("configmaps", labels.Everything(), labels.Everything())
-		})
-
-		It("should be success", func() {
-			cm := &corev1.ConfigMap{
-				ObjectMeta: metav1.ObjectMeta{
-					Namespace: "default",
-					Name:      "test",
-				},
-				Data: map[string]string{
-					"sample": "data",
-				},
+	It("should count updates for each manager", func() {
+		source := NewFakeSource()
+		watcher := startWatcher(source, false)
+		withManager := func(resourceVersion, manager string, t time.Time) *metav1.PartialObjectMetadata {
+			meta := newMeta("test", resourceVersion)
+			meta.ManagedFields = []metav1.ManagedFieldsEntry{
+				{Manager: manager, Operation: metav1.ManagedFieldsOperationUpdate, Time: &metav1.Time{Time: t}},
 			}
-			err := kubeClient.Cluster.GetClient().Create(ctx, cm)
-			Expect(err).NotTo(HaveOccurred())
-
-			Eventually(func(g Gomega) {
-				statistics := watcher.Statistics()
-				g.Expect(statistics.Namespaces).Should(MatchAllKeys(Keys{
-					"default": PointTo(MatchAllFields(Fields{
-						"Resources": MatchAllKeys(Keys{
-							"test": PointTo(MatchAllFields(Fields{
-								"AddCount":    Equal(1),
-								"UpdateCount": Equal(0),
-								"DeleteCount": Equal(0),
-							})),
-						}),
-					})),
-				}))
-			}).Should(Succeed())
-		})
+			return meta
+		}
+		now := time.Now()
+		source.Add(withManager("1", "controller", now))
+		source.Update(withManager("2", "controller", now.Add(time.Second)))
+		v3 := withManager("3", "kubectl", now.Add(2*time.Second))
+		source.Update(v3)
+		source.Update(withManager("4", "kubectl", now.Add(3*time.Second)))
+
+		Expect(watcher.ManagerUpdates("default", "test")).Should(Equal(map[string]int{"controller": 1, "kubectl": 2}))
+		Expect(watcher.ManagerSwitches("default", "test")).Should(Equal(1))
+		obj, ok := watcher.Object("default", "test")
+		Expect(ok).Should(BeTrue())
+		Expect(obj.ResourceVersion).Should(Equal("4"))
+		Expect(obj.GroupVersionKind()).Should(Equal(gvk))
+
+		source.Delete(v3)
+		Expect(watcher.ManagerUpdates("default", "test")).Should(BeEmpty())
+		Expect(watcher.ManagerSwitches("default", "test")).Should(BeZero())
 	})
 
-	Context("Watcher with namespace selector", func() {
-		BeforeEach(func() {
-			startWatcher("configmaps", labels.SelectorFromSet(map[string]string{"role": "admin"}), labels.Everything())
-		})
-
-		It("should only count configmap in admin-ns", func() {
-
-			cm1 := &corev1.ConfigMap{
-				ObjectMeta: metav1.ObjectMeta{
-					Namespace: "user-ns",
-					Name:      "test2",
-				},
-				Data: map[string]string{
-					"sample": "data",
-				},
-			}
-			err := kubeClient.Cluster.GetClient().Create(ctx, cm1)
-			Expect(err).NotTo(HaveOccurred())
-			cm2 := &corev1.ConfigMap{
-				ObjectMeta: metav1.ObjectMeta{
-					Namespace: "admin-ns",
-					Name:      "test1",
-				},
-				Data: map[string]string{
-					"sample": "data",
-				},
-			}
-			err = kubeClient.Cluster.GetClient().Create(ctx, cm2)
-			Expect(err).NotTo(HaveOccurred())
-
-			Eventually(func(g Gomega) {
-				statistics := watcher.Statistics()
-				g.Expect(statistics.Namespaces).Should(MatchAllKeys(Keys{
-					"admin-ns": PointTo(MatchAllFields(Fields{
-						"Resources": MatchAllKeys(Keys{
-							"test1": PointTo(MatchAllFields(Fields{
-								"AddCount":    Equal(1),
-								"UpdateCount": Equal(0),
-								"DeleteCount": Equal(0),
-							})),
-						}),
-					})),
-					// user-ns should not appear
-				}))
-			}).Should(Succeed())
-		})
+	It("should count relists and watch errors", func() {
+		source := NewFakeSource()
+		watcher := startWatcher(source, false)
+		source.Relist()
+		source.WatchError(errors.New("watch failed"))
+		source.WatchError(errors.New("watch failed"))
+
+		statistics := watcher.Statistics()
+		Expect(statistics.Relists).Should(Equal(1))
+		Expect(statistics.WatchErrors).Should(Equal(2))
 	})
 
-	Context("Watcher with resource selector", func() {
-		BeforeEach(func() {
-			selector, err := labels.Parse("ignored!=true")
-			Expect(err).NotTo(HaveOccurred())
-			startWatcher("configmaps", labels.Everything(), selector)
-		})
-
-		It("should only count configmap in admin-ns", func() {
-			cm1 := &corev1.ConfigMap{
-				ObjectMeta: metav1.ObjectMeta{
-					Namespace: "admin-ns",
-					Name:      "test1",
-					Labels: map[string]string{
-						"ignored": "true",
-					},
-				},
-				Data: map[string]string{
-					"sample": "data",
-				},
-			}
-			err := kubeClient.Cluster.GetClient().Create(ctx, cm1)
-			Expect(err).NotTo(HaveOccurred())
-
-			cm2 := &corev1.ConfigMap{
-				ObjectMeta: metav1.ObjectMeta{
-					Namespace: "user-ns",
-					Name:      "test2",
-				},
-				Data: map[string]string{
-					"sample": "data",
-				},
-			}
-			err = kubeClient.Cluster.GetClient().Create(ctx, cm2)
-			Expect(err).NotTo(HaveOccurred())
-
-			Eventually(func(g Gomega) {
-				statistics := watcher.Statistics()
-				g.Expect(statistics.Namespaces).Should(MatchAllKeys(Keys{
-					"user-ns": PointTo(MatchAllFields(Fields{
-						"Resources": MatchAllKeys(Keys{
-							"test2": PointTo(MatchAllFields(Fields{
-								"AddCount":    Equal(1),
-								"UpdateCount": Equal(0),
-								"DeleteCount": Equal(0),
-							})),
-						}),
-					})),
-					// admin-ns should not appear
-				}))
-			}).Should(Succeed())
-		})
+	It("should use the time of the events", func() {
+		base := time.Date(2023, 2, 17, 22, 25, 20, 0, time.UTC)
+		source := NewFakeSource()
+		now := base
+		source.Clock = func() time.Time { return now }
+		watcher := startWatcher(source, false)
+		source.Add(newMeta("test", "1"))
+		for i := 2; i <= 3; i++ {
+			now = now.Add(10 * time.Second)
+			source.Update(newMeta("test", fmt.Sprint(i)))
+		}
+
+		Expect(watcher.Statistics().Namespaces["default"].Resources["test"].MinInterval).Should(Equal(10.0))
 	})
+
+	DescribeTable("Detecting updates without changes",
+		func(oldObj, newObj interface{}, expected bool) {
+			Expect(isResync(oldObj, newObj)).Should(Equal(expected))
+		},
+		Entry("same resourceVersion", newMeta("test", "1"), newMeta("test", "1"), true),
+		Entry("different resourceVersion", newMeta("test", "1"), newMeta("test", "2"), false),
+		Entry("unknown object", "test", newMeta("test", "1"), false),
+	)
 })